@@ -23,6 +23,7 @@ import (
 
 	"github.com/maksim-paskal/aks-node-termination-handler/internal"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/tracing"
 	logrushooksentry "github.com/maksim-paskal/logrus-hook-sentry"
 	log "github.com/sirupsen/logrus"
 )
@@ -30,6 +31,24 @@ import (
 var version = flag.Bool("version", false, "version")
 
 func main() {
+	// subcommand to rehearse evictions on running handler
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		if err := internal.Simulate(context.Background(), os.Args[2:]); err != nil {
+			log.WithError(err).Fatal()
+		}
+
+		return
+	}
+
+	// subcommand to debug config that is built from flags, environment variables and config file
+	if len(os.Args) > 1 && os.Args[1] == "print-config" {
+		if err := internal.PrintConfig(context.Background(), os.Stdout, os.Args[2:]); err != nil {
+			log.WithError(err).Fatal()
+		}
+
+		return
+	}
+
 	flag.Parse()
 
 	if *version {
@@ -66,6 +85,7 @@ func main() {
 	}
 
 	log.AddHook(hook)
+	log.AddHook(tracing.LogHook{})
 
 	signalChanInterrupt := make(chan os.Signal, 1)
 	signal.Notify(signalChanInterrupt, syscall.SIGINT, syscall.SIGTERM)
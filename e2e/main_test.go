@@ -127,7 +127,7 @@ func checkNodeEvent(ctx context.Context) error { //nolint:cyclop
 			continue
 		}
 
-		if event.Reason == eventType && event.Message == config.EventMessageReceived {
+		if event.Reason == string(eventType) && event.Message == config.EventMessageReceived {
 			eventMessageReceived++
 		}
 
@@ -14,6 +14,13 @@ package internal
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/alert"
@@ -22,8 +29,13 @@ import (
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/client"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/hooks"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/imds"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/lease"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/metrics"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/status"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/template"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/tracing"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/web"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/webhook"
@@ -31,6 +43,12 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// events that node was drained for.
+var drainedEvents sync.Map
+
+// transport of webhook requests, it's instrumented once on start.
+var webhookTransport http.RoundTripper
+
 func Run(ctx context.Context) error {
 	err := config.Load()
 	if err != nil {
@@ -44,25 +62,66 @@ func Run(ctx context.Context) error {
 
 	log.Debugf("using config: %s", config.Get().String())
 
-	retryClient := retryablehttp.NewClient()
-	retryClient.HTTPClient.Transport = metrics.NewInstrumenter("webhook").
-		WithProxy(*config.Get().WebhookProxy).
-		WithInsecureSkipVerify(*config.Get().WebhookInsecure).
-		InstrumentedRoundTripper()
-	retryClient.RetryMax = *config.Get().WebhookRetries
-	webhook.SetHTTPClient(retryClient)
+	setBuildInfo()
+
+	shutdownTracing, err := tracing.Init(ctx, *config.Get().TracingEndpoint, *config.Get().TracingSampleRatio, config.GetVersion())
+	if err != nil {
+		return errors.Wrap(err, "error in init tracing")
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), config.Get().GracePeriod())
+		defer shutdownCancel()
+
+		if err := shutdownTracing(shutdownCtx); err != nil { //nolint:contextcheck
+			log.WithError(err).Error("error in shutdown tracing")
+		}
+	}()
+
+	// every attempt of webhook request is a separate span
+	webhookTransport = &tracing.Transport{
+		Name: "webhook.Request",
+		Next: metrics.NewInstrumenter("webhook").
+			WithProxy(*config.Get().WebhookProxy).
+			WithInsecureSkipVerify(*config.Get().WebhookInsecure).
+			InstrumentedRoundTripper(),
+	}
+
+	webhook.SetHTTPClient(newWebhookClient())
 
 	err = alert.Init()
 	if err != nil {
 		return errors.Wrap(err, "error in init alerts")
 	}
 
+	go config.WatchConfigFile(ctx, *config.Get().ConfigReloadPeriod, configReloaded)
+
+	go cache.SheduleCleaning(ctx)
+
+	// instance metadata is optional, it's requested when it's used first time
+	imds.SetEndpoint(*config.Get().InstanceEndpoint)
+
+	if config.Get().IsStandalone() {
+		go web.Start(ctx)
+
+		if err := startStandalone(ctx); err != nil {
+			return errors.Wrap(err, "error in startStandalone")
+		}
+
+		return nil
+	}
+
 	err = client.Init()
 	if err != nil {
 		return errors.Wrap(err, "error in init api")
 	}
 
-	go cache.SheduleCleaning(ctx)
+	if err := applyNodeOverrides(ctx); err != nil {
+		return errors.Wrap(err, "error in applyNodeOverrides")
+	}
+
 	go web.Start(ctx)
 
 	if err := startReadingEvents(ctx); err != nil {
@@ -78,12 +137,23 @@ func startReadingEvents(ctx context.Context) error {
 		return errors.Wrap(err, "error in getting azure resource name")
 	}
 
+	endpoint, err := events.ResolveEndpoint(ctx, *config.Get().Endpoint, *config.Get().APIVersion)
+	if err != nil {
+		return errors.Wrap(err, "error in resolving endpoint")
+	}
+
 	eventReader := events.NewReader()
 	eventReader.AzureResource = azureResource
 	eventReader.Period = *config.Get().Period
-	eventReader.Endpoint = *config.Get().Endpoint
+	eventReader.ScheduledPeriod = *config.Get().ScheduledPeriod
+	eventReader.MaxBackoff = *config.Get().MaxBackoff
+	eventReader.FailureThreshold = *config.Get().FailureThreshold
+	eventReader.Endpoint = endpoint
 	eventReader.RequestTimeout = *config.Get().RequestTimeout
 	eventReader.NodeName = *config.Get().NodeName
+
+	web.SetReader(eventReader)
+	web.SetNotifier(sendEvent)
 	eventReader.BeforeReading = func(ctx context.Context) error {
 		// add event to node
 		if err := api.AddNodeEvent(ctx, "Info", "ReadEvents", config.EventMessageBeforeListen); err != nil {
@@ -93,19 +163,41 @@ func startReadingEvents(ctx context.Context) error {
 		return nil
 	}
 
+	eventReader.ReadFailed = func(ctx context.Context, err error) {
+		addNodeEvent(ctx, "Warning", "ReadEventsFailed", fmt.Sprintf(config.EventMessageReadFailed, *config.Get().FailureThreshold, err.Error()))
+	}
+
 	eventReader.EventReceived = func(ctx context.Context, event types.ScheduledEventsEvent) (bool, error) {
+		status.RecordEvent(event)
+
 		// add event to node
-		if err := api.AddNodeEvent(ctx, "Warning", string(event.EventType), config.EventMessageReceived); err != nil {
+		if err := api.AddNodeEvent(ctx, "Warning", string(event.EventType), withSimulated(event, config.EventMessageReceived)); err != nil {
+			status.SetEventOutcome(event.EventId, status.OutcomeFailed, err)
+
 			return false, errors.Wrap(err, "error in add node event")
 		}
 
+		if len(*config.Get().NodeConditionType) > 0 {
+			if err := api.SetNodeCondition(ctx, *config.Get().NodeName, event); err != nil {
+				log.WithError(err).Error("error in set node condition")
+			}
+		}
+
 		// check if event is excludedm by default Freeze event is excluded
 		if config.Get().IsExcludedEvent(event.EventType) {
 			log.Infof("Excluded event %s by user config", event.EventType)
+			status.SetEventOutcome(event.EventId, status.OutcomeExcluded, nil)
 
 			return false, nil
 		}
 
+		// add event to pods that will be evicted
+		if *config.Get().PodEvents {
+			if err := api.AddPodsEventMessage(ctx, *config.Get().NodeName, newPodEventMessage(event)); err != nil {
+				log.WithError(err).Error("error in add pods event")
+			}
+		}
+
 		// send event in separate goroutine
 		go func() {
 			if err := sendEvent(ctx, event); err != nil {
@@ -113,14 +205,77 @@ func startReadingEvents(ctx context.Context) error {
 			}
 		}()
 
+		// limit number of nodes that drains at the same time
+		status.SetEventOutcome(event.EventId, status.OutcomeWaitingLease, nil)
+
+		releaseDrainLease, drainTime := acquireDrainLease(ctx, event)
+		defer releaseDrainLease()
+
+		status.SetEventOutcome(event.EventId, status.OutcomeDraining, nil)
+
+		// notify pods on node before eviction
+		runPodHooks(ctx, event, drainTime)
+
 		// drain node
-		if err := api.DrainNode(ctx, *config.Get().NodeName, string(event.EventType), event.EventId); err != nil {
+		if _, err := api.DrainNode(ctx, *config.Get().NodeName, string(event.EventType), event.EventId); err != nil {
+			status.SetEventOutcome(event.EventId, status.OutcomeFailed, err)
+
 			return false, errors.Wrap(err, "error in DrainNode")
 		}
 
-		return true, nil
+		status.SetEventOutcome(event.EventId, status.OutcomeDrained, nil)
+		drainedEvents.Store(event.EventId, true)
+
+		if err := deleteNodeIfStarted(ctx, azureResource, event); err != nil {
+			return false, errors.Wrap(err, "error in deleteNodeIfStarted")
+		}
+
+		// continue reading events to track when they are gone
+		return *config.Get().ExitAfterNodeDrain, nil
+	}
+
+	eventReader.EventUpdated = func(ctx context.Context, event types.ScheduledEventsEvent) error {
+		// condition shows current status of event
+		if len(*config.Get().NodeConditionType) > 0 {
+			if err := api.SetNodeCondition(ctx, *config.Get().NodeName, event); err != nil {
+				log.WithError(err).Error("error in set node condition")
+			}
+		}
+
+		// node can be deleted only after it was drained
+		if _, ok := drainedEvents.Load(event.EventId); !ok {
+			return nil
+		}
+
+		return deleteNodeIfStarted(ctx, azureResource, event)
+	}
+
+	eventReader.EventsCleared = func(ctx context.Context) error {
+		drainedEvents.Clear()
+
+		if len(*config.Get().NodeConditionType) > 0 {
+			if err := api.ClearNodeCondition(ctx, *config.Get().NodeName); err != nil {
+				return errors.Wrap(err, "error in clear node condition")
+			}
+		}
+
+		if *config.Get().UncordonAfterEvent {
+			if err := api.UncordonNode(ctx, *config.Get().NodeName); err != nil {
+				return errors.Wrap(err, "error in uncordon node")
+			}
+		}
+
+		return nil
+	}
+
+	// node is cleaned up on first read without events only if it has changes from previous events
+	hadEvents, err := api.HasPendingEvent(ctx, *config.Get().NodeName)
+	if err != nil {
+		log.WithError(err).Warn("error checking pending event on node")
 	}
 
+	eventReader.HadEvents = hadEvents
+
 	// check for run in synchronous mode or not
 	// synchronous mode is used for e2e tests
 	if *config.Get().ExitAfterNodeDrain {
@@ -132,6 +287,147 @@ func startReadingEvents(ctx context.Context) error {
 	return nil
 }
 
+// acquireDrainLease waits for drain lease for non urgent events, returned function releases lease,
+// returned duration is time before event starts that is reserved for drain after waiting for lease.
+func acquireDrainLease(ctx context.Context, event types.ScheduledEventsEvent) (func(), time.Duration) {
+	noLease := func() {}
+
+	if *config.Get().DrainConcurrency <= 0 {
+		return noLease, 0
+	}
+
+	// Preempt and Terminate events deletes virtual machine, drain can not wait
+	if event.EventType == types.EventTypePreempt || event.EventType == types.EventTypeTerminate {
+		metrics.DrainLeaseTotal.WithLabelValues("bypassed").Inc()
+
+		return noLease, 0
+	}
+
+	// node must be drained before event starts
+	if event.IsImminent(config.Get().NodeGracePeriod()) {
+		metrics.DrainLeaseTotal.WithLabelValues("bypassed").Inc()
+
+		return noLease, 0
+	}
+
+	timeout := event.TimeUntilStart() - config.Get().NodeGracePeriod()
+
+	semaphoreName, err := getDrainSemaphoreName(ctx)
+	if err != nil {
+		log.WithError(err).Error("error in getDrainSemaphoreName")
+
+		return noLease, 0
+	}
+
+	semaphore := lease.NewSemaphore(
+		client.GetKubernetesClient(),
+		*config.Get().DrainLeaseNamespace,
+		semaphoreName,
+		*config.Get().DrainConcurrency,
+		*config.Get().NodeName,
+	)
+
+	addNodeEvent(ctx, "Normal", "DrainLeaseWaiting", fmt.Sprintf(config.EventMessageLeaseWaiting, semaphoreName, time.Now().Add(timeout).Format(time.RFC1123)))
+
+	metrics.DrainLeaseWaiting.Set(1)
+	defer metrics.DrainLeaseWaiting.Set(0)
+
+	release, err := semaphore.Acquire(ctx, timeout)
+	if err != nil {
+		log.WithError(err).Warn("drain lease is not acquired")
+
+		metrics.DrainLeaseTotal.WithLabelValues("timeout").Inc()
+		addNodeEvent(ctx, "Warning", "DrainLeaseTimeout", fmt.Sprintf(config.EventMessageLeaseTimeout, semaphoreName))
+
+		return noLease, config.Get().NodeGracePeriod()
+	}
+
+	metrics.DrainLeaseTotal.WithLabelValues("acquired").Inc()
+	addNodeEvent(ctx, "Normal", "DrainLeaseAcquired", fmt.Sprintf(config.EventMessageLeaseAcquire, semaphoreName))
+
+	return release, config.Get().NodeGracePeriod()
+}
+
+// returns name of semaphore, semaphore can be cluster wide or per node pool.
+func getDrainSemaphoreName(ctx context.Context) (string, error) {
+	const semaphorePrefix = "aks-node-termination-handler-drain"
+
+	if *config.Get().DrainConcurrencyScope != config.DrainConcurrencyScopePool {
+		return semaphorePrefix, nil
+	}
+
+	nodeLabels, err := api.GetNodeLabels(ctx, *config.Get().NodeName)
+	if err != nil {
+		return "", errors.Wrap(err, "error in GetNodeLabels")
+	}
+
+	nodePool := nodeLabels["kubernetes.azure.com/agentpool"]
+	if len(nodePool) == 0 {
+		return semaphorePrefix, nil
+	}
+
+	return fmt.Sprintf("%s-%s", semaphorePrefix, strings.ToLower(nodePool)), nil
+}
+
+// runPodHooks sends event to pods that are opted in with annotations,
+// results of hooks are added as node events.
+func runPodHooks(ctx context.Context, event types.ScheduledEventsEvent, drainTime time.Duration) {
+	if *config.Get().PodHooksTimeout <= 0 || *config.Get().DryRun {
+		return
+	}
+
+	// timeout is computed after waiting for lease from time that is left before event starts
+	timeout := hooks.EventTimeout(event, *config.Get().PodHooksTimeout, drainTime)
+	if timeout <= 0 {
+		log.Warnf("Event %s starts in %s, pod hooks are skipped", event.EventId, event.TimeUntilStart())
+
+		return
+	}
+
+	pods, err := api.ListNodePods(ctx, *config.Get().NodeName)
+	if err != nil {
+		log.WithError(err).Error("error in ListNodePods")
+
+		return
+	}
+
+	for _, result := range hooks.RunPodHooks(ctx, pods, event, timeout) {
+		if result.Error != nil {
+			addNodeEvent(ctx, "Warning", "PodHookFailed", result.String())
+		} else {
+			addNodeEvent(ctx, "Normal", "PodHook", result.String())
+		}
+	}
+}
+
+// addNodeEvent adds informational event to node, errors are only logged.
+func addNodeEvent(ctx context.Context, eventType, eventReason, eventMessage string) {
+	if err := api.AddNodeEvent(ctx, eventType, eventReason, eventMessage); err != nil {
+		log.WithError(err).Error("error in add node event")
+	}
+}
+
+func deleteNodeIfStarted(ctx context.Context, azureResource string, event types.ScheduledEventsEvent) error {
+	if event.EventStatus != types.EventStatusStarted || !config.Get().IsDeleteNodeEvent(event.EventType) {
+		return nil
+	}
+
+	// virtual machine is not deleted by Azure when event is simulated
+	if event.IsSimulated() {
+		log.Infof("Simulated event %s, node is not deleted", event.EventId)
+
+		return nil
+	}
+
+	addNodeEvent(ctx, "Warning", "DeleteNode", config.EventMessageDeleteNode)
+
+	if err := api.DeleteNode(ctx, *config.Get().NodeName, azureResource); err != nil {
+		return errors.Wrap(err, "error in DeleteNode")
+	}
+
+	return nil
+}
+
 func sendEvent(ctx context.Context, event types.ScheduledEventsEvent) error {
 	message, err := template.NewMessageType(ctx, *config.Get().NodeName, event)
 	if err != nil {
@@ -142,13 +438,136 @@ func sendEvent(ctx context.Context, event types.ScheduledEventsEvent) error {
 
 	message.Template = *config.Get().AlertMessage
 
-	if err := alert.SendTelegram(message); err != nil {
-		log.WithError(err).Error("error in alert.SendTelegram")
+	if len(*config.Get().TelegramToken) > 0 {
+		_, span := tracing.Start(ctx, "notify.Telegram")
+
+		err := alert.SendTelegram(message)
+		if err != nil {
+			log.WithContext(ctx).WithError(err).Error("error in alert.SendTelegram")
+		}
+
+		tracing.End(span, err)
+		recordNotification("telegram", event.EventId, err)
+	}
+
+	if len(*config.Get().WebHookURL) > 0 {
+		ctx, span := tracing.Start(ctx, "notify.WebHook")
+
+		err := webhook.SendWebHook(ctx, message)
+		if err != nil {
+			log.WithContext(ctx).WithError(err).Error("error in webhook.SendWebHook")
+		}
+
+		tracing.End(span, err)
+		recordNotification("webhook", event.EventId, err)
+	}
+
+	return nil
+}
+
+func newWebhookClient() *retryablehttp.Client {
+	retryClient := retryablehttp.NewClient()
+	retryClient.HTTPClient.Transport = webhookTransport
+	// default logger prints full URL of every request, URL can contain token
+	retryClient.Logger = nil
+	retryClient.RetryMax = *config.Get().WebhookRetries
+
+	return retryClient
+}
+
+// called after config file is reloaded.
+func configReloaded(err error) {
+	metrics.ConfigReloadTotal.WithLabelValues(metrics.ResultLabel(err)).Inc()
+
+	if err != nil {
+		log.WithError(err).Error("config file is changed, but new config is not applied")
+
+		return
+	}
+
+	log.Info("config file is reloaded")
+
+	applyConfig()
+}
+
+// config of node pool is selected by labels of node.
+func applyNodeOverrides(ctx context.Context) error {
+	nodeLabels, err := api.GetNodeLabels(ctx, *config.Get().NodeName)
+	if err != nil {
+		return errors.Wrap(err, "error in getting node labels")
 	}
 
-	if err := webhook.SendWebHook(ctx, message); err != nil {
-		log.WithError(err).Error("error in webhook.SendWebHook")
+	if err := config.SetNodeLabels(nodeLabels); err != nil {
+		return errors.Wrap(err, "error in config.SetNodeLabels")
 	}
 
+	applyConfig()
+
 	return nil
 }
+
+// applies current config to clients that are created on start.
+func applyConfig() {
+	log.Debugf("using config: %s", config.Get().String())
+
+	webhook.SetHTTPClient(newWebhookClient())
+
+	if err := alert.Init(); err != nil {
+		log.WithError(err).Error("error in init alerts")
+	}
+
+	setBuildInfo()
+}
+
+// exports version and configuration of handler, values are used to compare handlers across clusters.
+func setBuildInfo() {
+	mode := "kubernetes"
+	if config.Get().IsStandalone() {
+		mode = "standalone"
+	}
+
+	evictionMode := "evict"
+	if *config.Get().DisableEviction {
+		evictionMode = "delete"
+	}
+
+	// configuration can be changed on reload
+	metrics.BuildInfo.Reset()
+	metrics.BuildInfo.WithLabelValues(
+		config.GetVersion(),
+		runtime.Version(),
+		mode,
+		strconv.FormatBool(*config.Get().DryRun),
+		*config.Get().TaintEffect,
+		evictionMode,
+		strconv.FormatBool(len(*config.Get().TelegramToken) > 0),
+		strconv.FormatBool(len(*config.Get().WebHookURL) > 0),
+	).Set(1)
+}
+
+func recordNotification(transport string, eventID string, err error) {
+	status.RecordNotification(transport, eventID, err)
+	metrics.NotificationsTotal.WithLabelValues(transport, metrics.ResultLabel(err)).Inc()
+}
+
+func newPodEventMessage(event types.ScheduledEventsEvent) *types.EventMessage {
+	return &types.EventMessage{
+		Type:   "Warning",
+		Reason: string(event.EventType),
+		Message: withSimulated(event, fmt.Sprintf(config.EventMessagePodEviction,
+			*config.Get().NodeName,
+			event.EventType,
+			event.EventId,
+			event.NotBeforeString(),
+		)),
+	}
+}
+
+// marks messages about simulated events, so nobody will think that eviction is real.
+func withSimulated(event types.ScheduledEventsEvent, message string) string {
+	if event.IsSimulated() {
+		return config.EventMessageSimulated + message
+	}
+
+	return message
+}
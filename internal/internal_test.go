@@ -0,0 +1,155 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package internal_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/internal"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/client"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	testNode      = "node1"
+	testNamespace = "kube-system"
+	testLease     = "aks-node-termination-handler-drain-0"
+)
+
+func setLeaseConfig(t *testing.T, drainConcurrency int) *fake.Clientset {
+	t.Helper()
+
+	clientset := fake.NewClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: testNode}})
+	client.SetKubernetesClient(clientset)
+
+	t.Cleanup(func() {
+		client.SetKubernetesClient(nil)
+	})
+
+	nodeName := testNode
+	namespace := testNamespace
+	scope := config.DrainConcurrencyScopeCluster
+	nodeGracePeriod := 1
+
+	config.Set(config.Type{
+		NodeName:               &nodeName,
+		EventsNamespace:        &namespace,
+		DrainLeaseNamespace:    &namespace,
+		DrainConcurrency:       &drainConcurrency,
+		DrainConcurrencyScope:  &scope,
+		NodeGracePeriodSeconds: &nodeGracePeriod,
+	})
+
+	return clientset
+}
+
+func newEvent(eventType types.ScheduledEventsEventType, untilStart time.Duration) types.ScheduledEventsEvent {
+	notBefore := time.Now().Add(untilStart)
+
+	return types.ScheduledEventsEvent{
+		EventId:   "id1",
+		EventType: eventType,
+		NotBefore: &notBefore,
+	}
+}
+
+func getLeaseHolder(t *testing.T, clientset *fake.Clientset) string {
+	t.Helper()
+
+	lease, err := clientset.CoordinationV1().Leases(testNamespace).Get(context.TODO(), testLease, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	return ptr.Deref(lease.Spec.HolderIdentity, "")
+}
+
+//nolint:paralleltest
+func TestAcquireDrainLeaseDisabled(t *testing.T) {
+	clientset := setLeaseConfig(t, 0)
+
+	release, drainTime := internal.AcquireDrainLease(context.TODO(), newEvent(types.EventTypeReboot, time.Hour))
+	release()
+
+	assert.Zero(t, drainTime)
+
+	leases, err := clientset.CoordinationV1().Leases(testNamespace).List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, leases.Items)
+}
+
+//nolint:paralleltest
+func TestAcquireDrainLeaseBypassed(t *testing.T) {
+	clientset := setLeaseConfig(t, 1)
+
+	// virtual machine is deleted after Preempt event, drain can not wait for lease
+	release, drainTime := internal.AcquireDrainLease(context.TODO(), newEvent(types.EventTypePreempt, time.Hour))
+	release()
+
+	assert.Zero(t, drainTime)
+
+	leases, err := clientset.CoordinationV1().Leases(testNamespace).List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, leases.Items)
+}
+
+//nolint:paralleltest
+func TestAcquireDrainLeaseReleased(t *testing.T) {
+	clientset := setLeaseConfig(t, 1)
+
+	release, drainTime := internal.AcquireDrainLease(context.TODO(), newEvent(types.EventTypeReboot, time.Hour))
+
+	assert.Equal(t, testNode, getLeaseHolder(t, clientset))
+
+	// pod hooks must not use time that is reserved for drain
+	assert.Equal(t, config.Get().NodeGracePeriod(), drainTime)
+
+	release()
+
+	assert.Empty(t, getLeaseHolder(t, clientset))
+}
+
+//nolint:paralleltest
+func TestAcquireDrainLeaseTimeout(t *testing.T) {
+	clientset := setLeaseConfig(t, 1)
+
+	// lease is held by other node
+	_, err := clientset.CoordinationV1().Leases(testNamespace).Create(context.TODO(), &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: testLease, Namespace: testNamespace},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("node2"),
+			LeaseDurationSeconds: ptr.To(int32(60)),
+			RenewTime:            ptr.To(metav1.NewMicroTime(time.Now())),
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	startTime := time.Now()
+
+	// drain starts without lease when node grace period is reached
+	release, drainTime := internal.AcquireDrainLease(context.TODO(), newEvent(types.EventTypeReboot, 3*time.Second))
+	release()
+
+	assert.Equal(t, config.Get().NodeGracePeriod(), drainTime)
+
+	assert.Less(t, time.Since(startTime), 3*time.Second)
+	assert.Equal(t, "node2", getLeaseHolder(t, clientset))
+}
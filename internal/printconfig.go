@@ -0,0 +1,84 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package internal
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/api"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/client"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// PrintConfig writes effective config with sources of values, args are flags of handler.
+func PrintConfig(ctx context.Context, w io.Writer, args []string) error {
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return errors.Wrap(err, "error parsing flags")
+	}
+
+	if err := config.Load(); err != nil {
+		return errors.Wrap(err, "error in config.Load")
+	}
+
+	// overrides are shown when node labels can be read
+	if !config.Get().IsStandalone() && len(*config.Get().NodeName) > 0 {
+		if err := printConfigNodeLabels(ctx); err != nil {
+			return err
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0) //nolint:mnd
+
+	_, _ = fmt.Fprintln(tw, "FIELD\tFLAG\tENV\tSOURCE\tVALUE")
+
+	for _, option := range config.Describe() {
+		_, _ = fmt.Fprintf(tw, "%s\t-%s\t%s\t%s\t%s\n", option.Field, option.Flag, option.Env, option.Source, option.Value)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "error in tabwriter.Flush")
+	}
+
+	if err := config.Check(); err != nil {
+		return errors.Wrap(err, "config is not valid")
+	}
+
+	return nil
+}
+
+func printConfigNodeLabels(ctx context.Context) error {
+	if err := client.Init(); err != nil {
+		log.WithError(err).Warn("node overrides are not applied")
+
+		return nil
+	}
+
+	nodeLabels, err := api.GetNodeLabels(ctx, *config.Get().NodeName)
+	if err != nil {
+		log.WithError(err).Warn("node overrides are not applied")
+
+		return nil
+	}
+
+	if err := config.SetNodeLabels(nodeLabels); err != nil {
+		return errors.Wrap(err, "error in config.SetNodeLabels")
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/web"
+	"github.com/pkg/errors"
+)
+
+const simulateTimeout = 10 * time.Second
+
+var errSimulateFailed = errors.New("simulate request failed")
+
+// Simulate sends synthetic event to running handler, args are arguments of simulate subcommand.
+func Simulate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+
+	address := flags.String("address", "http://127.0.0.1:17923", "address of handler web server")
+	token := flags.String("token", os.Getenv("DRAIN_TOKEN"), "bearer token, Kubernetes service account token or -web.drainToken of handler")
+	eventType := flags.String("type", string(types.EventTypePreempt), "type of simulated event")
+	eventStatus := flags.String("status", string(types.EventStatusScheduled), "status of simulated event, Scheduled or Started")
+	notBefore := flags.Duration("notBefore", 0, "offset of NotBefore from now")
+	clearEvent := flags.Bool("clear", false, "simulate that event is gone after it was processed, node will be uncordoned with -uncordonAfterEvent")
+
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "error parsing flags")
+	}
+
+	body, err := json.Marshal(web.SimulateRequest{
+		EventType:   types.ScheduledEventsEventType(*eventType),
+		EventStatus: types.EventStatus(*eventStatus),
+		NotBefore:   notBefore.String(),
+		ClearEvent:  *clearEvent,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error in json.Marshal")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, simulateTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(*address, "/")+"/simulate", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error in http.NewRequestWithContext")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(*token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error in client.Do")
+	}
+	defer resp.Body.Close()
+
+	result, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "error in io.ReadAll")
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		return errors.Wrapf(errSimulateFailed, "StatusCode=%d, %s", resp.StatusCode, strings.TrimSpace(string(result)))
+	}
+
+	fmt.Print(string(result)) //nolint:forbidigo
+
+	return nil
+}
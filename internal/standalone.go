@@ -0,0 +1,112 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package internal
+
+import (
+	"context"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/hooks"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/imds"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/status"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/web"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var errNoResourceName = errors.New("resource name is not defined and instance metadata is not available")
+
+// startStandalone reads events without Kubernetes, on every event
+// notifications are sent and exec hooks are run.
+func startStandalone(ctx context.Context) error {
+	azureResource, err := getStandaloneResourceName(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error in getting azure resource name")
+	}
+
+	// node name is used in notifications
+	if len(*config.Get().NodeName) == 0 {
+		*config.Get().NodeName = azureResource
+	}
+
+	endpoint, err := events.ResolveEndpoint(ctx, *config.Get().Endpoint, *config.Get().APIVersion)
+	if err != nil {
+		return errors.Wrap(err, "error in resolving endpoint")
+	}
+
+	eventReader := events.NewReader()
+	eventReader.AzureResource = azureResource
+	eventReader.Period = *config.Get().Period
+	eventReader.ScheduledPeriod = *config.Get().ScheduledPeriod
+	eventReader.MaxBackoff = *config.Get().MaxBackoff
+	eventReader.FailureThreshold = *config.Get().FailureThreshold
+	eventReader.Endpoint = endpoint
+	eventReader.RequestTimeout = *config.Get().RequestTimeout
+	eventReader.NodeName = *config.Get().NodeName
+
+	web.SetReader(eventReader)
+
+	eventReader.EventReceived = func(ctx context.Context, event types.ScheduledEventsEvent) (bool, error) {
+		status.RecordEvent(event)
+
+		if config.Get().IsExcludedEvent(event.EventType) {
+			log.Infof("Excluded event %s by user config", event.EventType)
+			status.SetEventOutcome(event.EventId, status.OutcomeExcluded, nil)
+
+			return false, nil
+		}
+
+		// send event in separate goroutine
+		go func() {
+			if err := sendEvent(ctx, event); err != nil {
+				log.WithError(err).Error("error in sendEvent")
+			}
+		}()
+
+		if *config.Get().DryRun {
+			log.Infof("Dry run, exec hooks for event %s are skipped", event.EventId)
+			status.SetEventOutcome(event.EventId, status.OutcomeDryRun, nil)
+		} else {
+			// commands are validated on start
+			commands, _ := config.Get().ExecHooksList()
+
+			hooks.RunExecHooks(ctx, commands, azureResource, event, *config.Get().ExecHooksTimeout)
+			status.SetEventOutcome(event.EventId, status.OutcomeHooksRun, nil)
+		}
+
+		return *config.Get().ExitAfterNodeDrain, nil
+	}
+
+	if *config.Get().ExitAfterNodeDrain {
+		eventReader.ReadEvents(ctx)
+	} else {
+		go eventReader.ReadEvents(ctx)
+	}
+
+	return nil
+}
+
+// returns user defined resource name or name from instance metadata.
+func getStandaloneResourceName(ctx context.Context) (string, error) {
+	if len(*config.Get().ResourceName) > 0 {
+		return *config.Get().ResourceName, nil
+	}
+
+	if compute := imds.Get(ctx); compute != nil {
+		return compute.EventResourceName(), nil
+	}
+
+	return "", errNoResourceName
+}
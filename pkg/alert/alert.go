@@ -14,6 +14,7 @@ package alert
 
 import (
 	"strconv"
+	"sync/atomic"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
@@ -22,31 +23,64 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var bot *tgbotapi.BotAPI
+var errBotNotInitialized = errors.New("Telegram bot is not initialized with current token")
 
+type telegramBot struct {
+	token string
+	api   *tgbotapi.BotAPI
+}
+
+// bot is replaced on config reload while notifications are sent.
+var bot atomic.Pointer[telegramBot]
+
+// Init creates Telegram bot, bot is created again only if token was changed,
+// creating bot is a request to Telegram API.
 func Init() error {
-	if len(*config.Get().TelegramToken) == 0 {
-		log.Warning("not sending Telegram message, no token")
+	token := *config.Get().TelegramToken
 
+	if current := bot.Load(); current != nil && current.token == token {
 		return nil
 	}
 
-	var err error
+	if len(token) == 0 {
+		log.Warning("not sending Telegram message, no token")
 
-	bot, err = tgbotapi.NewBotAPI(*config.Get().TelegramToken)
+		bot.Store(&telegramBot{})
+
+		return nil
+	}
+
+	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return errors.Wrap(err, "error in NewBotAPI")
 	}
 
-	log.Printf("Authorized on account %s", bot.Self.UserName)
+	log.Printf("Authorized on account %s", api.Self.UserName)
+
+	bot.Store(&telegramBot{token: token, api: api})
 
 	return nil
 }
 
+// returns bot that was created with current token.
+func getBot() (*tgbotapi.BotAPI, error) {
+	current := bot.Load()
+	if current == nil || current.api == nil || current.token != *config.Get().TelegramToken {
+		return nil, errBotNotInitialized
+	}
+
+	return current.api, nil
+}
+
 // healthcheck.
 func Ping() error {
 	if len(*config.Get().TelegramToken) != 0 {
-		if _, err := bot.GetMe(); err != nil {
+		api, err := getBot()
+		if err != nil {
+			return err
+		}
+
+		if _, err := api.GetMe(); err != nil {
 			return errors.Wrap(err, "error in bot.GetMe")
 		}
 	}
@@ -64,14 +98,23 @@ func SendTelegram(obj *template.MessageType) error {
 		return errors.Wrap(err, "error in template.Message")
 	}
 
+	if obj.Simulated {
+		messageText = config.EventMessageSimulated + messageText
+	}
+
 	chatID, err := strconv.Atoi(*config.Get().TelegramChatID)
 	if err != nil {
 		return errors.Wrap(err, "error converting chatID")
 	}
 
+	api, err := getBot()
+	if err != nil {
+		return err
+	}
+
 	msg := tgbotapi.NewMessage(int64(chatID), messageText)
 
-	result, err := bot.Send(msg)
+	result, err := api.Send(msg)
 	if err != nil {
 		return errors.Wrap(err, "error in bot.Send")
 	}
@@ -14,25 +14,42 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/client"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/imds"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/logger"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/metrics"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/tracing"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	apierrorrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/kubectl/pkg/drain"
 )
 
-const taintKeyPrefix = "aks-node-termination-handler"
+const (
+	taintKeyPrefix     = "aks-node-termination-handler"
+	fieldManager       = "aks-node-termination-handler"
+	drainedAnnotation  = "aks-node-termination-handler/drained"
+	autoscalerTaintKey = "ToBeDeletedByClusterAutoscaler"
+)
 
 func GetAzureResourceName(ctx context.Context, nodeName string) (string, error) {
 	// return user defined resource name
@@ -47,40 +64,318 @@ func GetAzureResourceName(ctx context.Context, nodeName string) (string, error)
 
 	azureResourceName, err := types.NewAzureResource(node.Spec.ProviderID)
 	if err != nil {
-		return "", errors.Wrap(err, "error in types.NewAzureResource")
+		// instance metadata is requested only if providerID is not recognized
+		compute := imds.Get(ctx)
+		if compute == nil {
+			return "", errors.Wrap(err, "error in types.NewAzureResource")
+		}
+
+		log.WithError(err).Warnf("using resource name %s from instance metadata", compute.EventResourceName())
+
+		return compute.EventResourceName(), nil
 	}
 
 	return azureResourceName.EventResourceName, nil
 }
 
-func DrainNode(ctx context.Context, nodeName string, eventType string, eventID string) error { //nolint:cyclop
-	log.Infof("Draining node %s", nodeName)
+// DrainResult describes pods that were evicted or deleted from node.
+type DrainResult struct {
+	EvictedPods []string `json:"evictedPods"`
+	Failures    []string `json:"failures"`
+	mutex       sync.Mutex
+	// context of drain and spans of pods that are evicting now
+	ctx      context.Context //nolint:containedctx
+	podSpans map[k8stypes.UID]trace.Span
+}
+
+func newDrainResult(ctx context.Context) *DrainResult {
+	return &DrainResult{
+		EvictedPods: make([]string, 0),
+		Failures:    make([]string, 0),
+		ctx:         ctx,
+		podSpans:    make(map[k8stypes.UID]trace.Span),
+	}
+}
+
+func (r *DrainResult) podStarted(pod *corev1.Pod, usingEviction bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, span := tracing.Start(r.ctx, "api.EvictPod", trace.WithAttributes(
+		attribute.String("pod.namespace", pod.Namespace),
+		attribute.String("pod.name", pod.Name),
+		attribute.Bool("pod.eviction", usingEviction),
+	))
+
+	r.podSpans[pod.UID] = span
+}
+
+// pods are evicted in parallel.
+func (r *DrainResult) podFinished(pod *corev1.Pod, usingEviction bool, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// start is not reported when pod is deleted without eviction
+	span, ok := r.podSpans[pod.UID]
+	if !ok {
+		_, span = tracing.Start(r.ctx, "api.DeletePod", trace.WithAttributes(
+			attribute.String("pod.namespace", pod.Namespace),
+			attribute.String("pod.name", pod.Name),
+			attribute.Bool("pod.eviction", usingEviction),
+		))
+	}
+
+	delete(r.podSpans, pod.UID)
+	tracing.End(span, err)
+
+	podName := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+
+	switch {
+	case err != nil:
+		r.Failures = append(r.Failures, fmt.Sprintf("%s: %s", podName, err.Error()))
+		metrics.DrainPodsTotal.WithLabelValues(pod.Namespace, "failed").Inc()
+	case usingEviction:
+		r.EvictedPods = append(r.EvictedPods, podName)
+		metrics.DrainPodsTotal.WithLabelValues(pod.Namespace, "evicted").Inc()
+	default:
+		r.EvictedPods = append(r.EvictedPods, podName)
+		metrics.DrainPodsTotal.WithLabelValues(pod.Namespace, "deleted").Inc()
+	}
+}
+
+// DrainNode drains node, result contains evicted pods even if drain failed.
+func DrainNode(ctx context.Context, nodeName string, eventType string, eventID string) (*DrainResult, error) {
+	// manual and scheduled drains can run at the same time
+	metrics.NodeDraining.Inc()
+	defer metrics.NodeDraining.Dec()
+
+	ctx, span := tracing.Start(ctx, "api.DrainNode", trace.WithAttributes(attribute.String("node.name", nodeName)))
+
+	start := time.Now()
+
+	result, err := drainNode(ctx, nodeName, eventType, eventID)
+
+	outcome := getDrainOutcome(err)
+
+	span.SetAttributes(attribute.String("drain.outcome", outcome))
+	tracing.End(span, err)
+
+	metrics.DrainTotal.WithLabelValues(outcome).Inc()
+	metrics.DrainDurationSeconds.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+
+	return result, err
+}
+
+func getDrainOutcome(err error) string {
+	switch {
+	case err == nil && *config.Get().DryRun:
+		return "dry-run"
+	case err == nil:
+		return "success"
+	// drain helper returns errors of eviction with global timeout as text
+	case errors.Is(err, context.DeadlineExceeded), wait.Interrupted(err), strings.Contains(err.Error(), "global timeout reached"):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
+
+func drainNode(ctx context.Context, nodeName string, eventType string, eventID string) (*DrainResult, error) { //nolint:cyclop,funlen
+	log.WithContext(ctx).Infof("Draining node %s", nodeName)
+
+	result := newDrainResult(ctx)
 
 	node, err := GetNode(ctx, nodeName)
 	if err != nil {
-		return errors.Wrap(err, "error in nodes.get")
+		return result, errors.Wrap(err, "error in nodes.get")
 	}
 
 	if node.Spec.Unschedulable {
 		log.Infof("Node %s is already Unschedulable", node.Name)
 
-		return nil
+		return result, nil
 	}
 
 	// taint node before draining if effect is NoSchedule or TaintEffectPreferNoSchedule
 	if *config.Get().TaintNode && *config.Get().TaintEffect != string(corev1.TaintEffectNoExecute) {
-		err = addTaint(ctx, node, getTaintKey(eventType), eventID)
+		err = addTaint(ctx, node, getEventTaint(eventType, eventID))
+		if err != nil {
+			return result, errors.Wrap(err, "failed to taint node")
+		}
+	}
+
+	// mark node for cluster autoscaler, so it can start replacement capacity earlier
+	if *config.Get().TaintAutoscaler {
+		err = addTaint(ctx, node, getAutoscalerTaint())
+		if err != nil {
+			return result, errors.Wrap(err, "failed to taint node")
+		}
+	}
+
+	nodeAnnotations, err := config.Get().NodeAnnotationsMap()
+	if err != nil {
+		return result, errors.Wrap(err, "error in NodeAnnotationsMap")
+	}
+
+	if len(nodeAnnotations) > 0 {
+		addAnnotations := make(map[string]interface{}, len(nodeAnnotations))
+
+		for key, value := range nodeAnnotations {
+			addAnnotations[key] = value
+		}
+
+		if err := patchNodeAnnotations(ctx, node.Name, addAnnotations); err != nil {
+			return result, errors.Wrap(err, "failed to annotate node")
+		}
+	}
+
+	if *config.Get().DryRun {
+		log.Infof("DRY RUN ENABLED; skipping cordoning and draining of node %s", node.Name)
+	} else {
+		if err := cordonNode(ctx, node); err != nil {
+			return result, err
+		}
+
+		// mark node as cordoned by handler, it's used to revert changes on uncordon,
+		// node that was cordoned before event is not uncordoned
+		if err := patchNodeAnnotations(ctx, node.Name, map[string]interface{}{drainedAnnotation: eventID}); err != nil {
+			return result, errors.Wrap(err, "failed to annotate node")
+		}
+
+		drainCtx, span := tracing.Start(ctx, "api.RunNodeDrain")
+
+		helper := newDrainHelper(drainCtx)
+		helper.OnPodDeletionOrEvictionStarted = result.podStarted
+		helper.OnPodDeletionOrEvictionFinished = result.podFinished
+
+		result.ctx = drainCtx
+
+		err := drain.RunNodeDrain(helper, node.Name)
+
+		tracing.End(span, err)
+
 		if err != nil {
-			return errors.Wrap(err, "failed to taint node")
+			return result, errors.Wrap(err, "error in drain.RunNodeDrain")
 		}
 	}
 
+	// taint node after draining if effect is TaintEffectNoExecute
+	// this NoExecute taint effect will stop all daemonsents on the node that can not handle this effect
+	if *config.Get().TaintNode && *config.Get().TaintEffect == string(corev1.TaintEffectNoExecute) {
+		err = addTaint(ctx, node, getEventTaint(eventType, eventID))
+		if err != nil {
+			return result, errors.Wrap(err, "failed to taint node")
+		}
+	}
+
+	return result, nil
+}
+
+func cordonNode(ctx context.Context, node *corev1.Node) error {
+	ctx, span := tracing.Start(ctx, "api.CordonNode")
+
+	err := drain.RunCordonOrUncordon(newDrainHelper(ctx), node, true)
+
+	tracing.End(span, err)
+
+	if err != nil {
+		return errors.Wrap(err, "error in drain.RunCordonOrUncordon")
+	}
+
+	return nil
+}
+
+// DeleteNode deletes node object if node still belongs to Azure resource.
+func DeleteNode(ctx context.Context, nodeName string, azureResource string) error {
+	node, err := GetNode(ctx, nodeName)
+	if err != nil {
+		return errors.Wrap(err, "error in nodes.get")
+	}
+
+	// node name can be reused by another virtual machine, resource name from -resource.name
+	// or from instance metadata is not derived from providerID and can not be compared
+	if len(*config.Get().ResourceName) == 0 {
+		nodeResource, err := types.NewAzureResource(node.Spec.ProviderID)
+		if err == nil && !strings.EqualFold(nodeResource.EventResourceName, azureResource) {
+			return errors.Errorf("node %s providerID %s does not match resource %s", node.Name, node.Spec.ProviderID, azureResource)
+		}
+	}
+
+	if *config.Get().DryRun {
+		log.Infof("DRY RUN ENABLED; skipping deleting of node %s", node.Name)
+
+		return nil
+	}
+
+	log.Warnf("Deleting node %s", node.Name)
+
+	err = client.GetKubernetesClient().CoreV1().Nodes().Delete(ctx, node.Name, metav1.DeleteOptions{
+		Preconditions: metav1.NewUIDPreconditions(string(node.UID)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error in nodes.delete")
+	}
+
+	return nil
+}
+
+// UncordonNode reverts all changes that was made to node while draining.
+func UncordonNode(ctx context.Context, nodeName string) error {
+	node, err := GetNode(ctx, nodeName)
+	if err != nil {
+		return errors.Wrap(err, "error in nodes.get")
+	}
+
+	if _, ok := node.Annotations[drainedAnnotation]; !ok {
+		log.Infof("Node %s was not drained by handler, skipping uncordon", node.Name)
+
+		return nil
+	}
+
+	log.Infof("Uncordon node %s", node.Name)
+
+	err = removeTaints(ctx, node.Name, func(taint corev1.Taint) bool {
+		return strings.HasPrefix(taint.Key, taintKeyPrefix+"/") || taint.Key == autoscalerTaintKey
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to remove taints")
+	}
+
+	if *config.Get().DryRun {
+		log.Infof("DRY RUN ENABLED; skipping uncordoning of node %s", node.Name)
+	} else {
+		if err := drain.RunCordonOrUncordon(newDrainHelper(ctx), node, false); err != nil {
+			return errors.Wrap(err, "error in drain.RunCordonOrUncordon")
+		}
+	}
+
+	nodeAnnotations, err := config.Get().NodeAnnotationsMap()
+	if err != nil {
+		return errors.Wrap(err, "error in NodeAnnotationsMap")
+	}
+
+	removeAnnotations := map[string]interface{}{
+		drainedAnnotation: nil,
+	}
+
+	for key := range nodeAnnotations {
+		removeAnnotations[key] = nil
+	}
+
+	if err := patchNodeAnnotations(ctx, node.Name, removeAnnotations); err != nil {
+		return errors.Wrap(err, "failed to remove node annotations")
+	}
+
+	return nil
+}
+
+func newDrainHelper(ctx context.Context) *drain.Helper {
 	logger := &logger.KubectlLogger{}
 	logger.Log = func(message string) {
 		log.Info(message)
 	}
 
-	helper := &drain.Helper{
+	return &drain.Helper{
 		Ctx:                 ctx,
 		Client:              client.GetKubernetesClient(),
 		Force:               true,
@@ -92,95 +387,288 @@ func DrainNode(ctx context.Context, nodeName string, eventType string, eventID s
 		Timeout:             config.Get().NodeGracePeriod(),
 		DisableEviction:     *config.Get().DisableEviction,
 	}
+}
+
+// PodsForDeletion returns pods that will be evicted or deleted on node drain.
+func PodsForDeletion(ctx context.Context, nodeName string) ([]string, error) {
+	podDeleteList, errs := newDrainHelper(ctx).GetPodsForDeletion(nodeName)
+	if len(errs) > 0 {
+		return nil, errors.Wrap(utilerrors.NewAggregate(errs), "error in GetPodsForDeletion")
+	}
+
+	pods := make([]string, 0)
+
+	for _, pod := range podDeleteList.Pods() {
+		pods = append(pods, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+	}
+
+	return pods, nil
+}
 
+// patchNodeAnnotations merges annotations to node, nil value removes annotation.
+func patchNodeAnnotations(ctx context.Context, nodeName string, annotations map[string]interface{}) error {
 	if *config.Get().DryRun {
-		log.Infof("DRY RUN ENABLED; skipping cordoning and draining of node %s", node.Name)
-	} else {
-		if err := drain.RunCordonOrUncordon(helper, node, true); err != nil {
-			return errors.Wrap(err, "error in drain.RunCordonOrUncordon")
-		}
+		log.Infof("DRY RUN ENABLED; skipping patching annotations %+v on node %s", annotations, nodeName)
 
-		if err := drain.RunNodeDrain(helper, node.Name); err != nil {
-			return errors.Wrap(err, "error in drain.RunNodeDrain")
-		}
+		return nil
 	}
 
-	// taint node after draining if effect is TaintEffectNoExecute
-	// this NoExecute taint effect will stop all daemonsents on the node that can not handle this effect
-	if *config.Get().TaintNode && *config.Get().TaintEffect == string(corev1.TaintEffectNoExecute) {
-		err = addTaint(ctx, node, getTaintKey(eventType), eventID)
-		if err != nil {
-			return errors.Wrap(err, "failed to taint node")
-		}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error in json.Marshal")
+	}
+
+	_, err = client.GetKubernetesClient().CoreV1().Nodes().Patch(ctx, nodeName, k8stypes.MergePatchType, patch, metav1.PatchOptions{
+		FieldManager: fieldManager,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error in nodes.patch")
 	}
 
 	return nil
 }
 
+func getEventTaint(eventType string, eventID string) corev1.Taint {
+	return corev1.Taint{
+		Key:    getTaintKey(eventType),
+		Value:  eventID,
+		Effect: corev1.TaintEffect(*config.Get().TaintEffect),
+	}
+}
+
+// cluster autoscaler uses this taint for nodes that will be deleted.
+func getAutoscalerTaint() corev1.Taint {
+	return corev1.Taint{
+		Key:    autoscalerTaintKey,
+		Value:  strconv.FormatInt(time.Now().Unix(), 10),
+		Effect: corev1.TaintEffectNoSchedule,
+	}
+}
+
 func getTaintKey(eventType string) string {
 	return fmt.Sprintf("%s/%s", taintKeyPrefix, strings.ToLower(eventType))
 }
 
-func addTaint(ctx context.Context, node *corev1.Node, taintKey string, taintValue string) error {
-	log.Infof("Adding taint %s=%s on node %s", taintKey, taintValue, node.Name)
+func addTaint(ctx context.Context, node *corev1.Node, newTaint corev1.Taint) error {
+	log.WithContext(ctx).Infof("Adding taint %s=%s on node %s", newTaint.Key, newTaint.Value, node.Name)
+
+	ctx, span := tracing.Start(ctx, "api.AddTaint", trace.WithAttributes(attribute.String("taint.key", newTaint.Key)))
+
+	err := patchNodeTaints(ctx, node.Name, func(taints []corev1.Taint) ([]corev1.Taint, bool) {
+		for i, taint := range taints {
+			// taint is identified by key and effect
+			if taint.Key == newTaint.Key && taint.Effect == newTaint.Effect {
+				if taint.Value == newTaint.Value || newTaint.Key == autoscalerTaintKey {
+					return taints, false
+				}
+
+				taints[i].Value = newTaint.Value
+
+				return taints, true
+			}
+		}
+
+		return append(taints, newTaint), true
+	})
+
+	tracing.End(span, err)
+	metrics.TaintOperationsTotal.WithLabelValues("add", metrics.ResultLabel(err)).Inc()
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to taint node %s with key %s", node.Name, newTaint.Key)
+	}
+
+	log.Warnf("Successfully added taint %s on node %s", newTaint.Key, node.Name)
+
+	return nil
+}
+
+// RemoveTaint removes all taints with key from node.
+func RemoveTaint(ctx context.Context, nodeName string, taintKey string) error {
+	return removeTaints(ctx, nodeName, func(taint corev1.Taint) bool {
+		return taint.Key == taintKey
+	})
+}
 
-	freshNode := node.DeepCopy()
+func removeTaints(ctx context.Context, nodeName string, match func(corev1.Taint) bool) error {
+	ctx, span := tracing.Start(ctx, "api.RemoveTaints")
 
-	var err error
+	err := patchNodeTaints(ctx, nodeName, func(taints []corev1.Taint) ([]corev1.Taint, bool) {
+		result := make([]corev1.Taint, 0, len(taints))
 
-	updateErr := wait.ExponentialBackoff(retry.DefaultBackoff, func() (bool, error) {
-		if freshNode, err = client.GetKubernetesClient().CoreV1().Nodes().Get(ctx, freshNode.Name, metav1.GetOptions{}); err != nil {
-			nodeErr := errors.Wrapf(err, "failed to get node %s", freshNode.Name)
-			log.Error(nodeErr)
+		for _, taint := range taints {
+			if match(taint) {
+				log.Infof("Removing taint %s on node %s", taint.Key, nodeName)
 
-			return false, nodeErr
+				continue
+			}
+
+			result = append(result, taint)
 		}
 
-		err = updateNodeWith(ctx, taintKey, taintValue, freshNode)
+		return result, len(result) != len(taints)
+	})
+
+	tracing.End(span, err)
+	metrics.TaintOperationsTotal.WithLabelValues("remove", metrics.ResultLabel(err)).Inc()
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove taints from node %s", nodeName)
+	}
+
+	return nil
+}
+
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// patchNodeTaints applies changes of node taints with JSON patch,
+// update function returns new taints and true if taints was changed.
+func patchNodeTaints(ctx context.Context, nodeName string, update func([]corev1.Taint) ([]corev1.Taint, bool)) error {
+	return wait.ExponentialBackoff(retry.DefaultBackoff, func() (bool, error) {
+		node, err := GetNode(ctx, nodeName)
+		if err != nil {
+			return false, errors.Wrap(err, "error in GetNode")
+		}
+
+		taints, changed := update(node.Spec.Taints)
+		if !changed {
+			return true, nil
+		}
+
+		if *config.Get().DryRun {
+			log.Infof("DRY RUN ENABLED; skipping patching taints %+v on node %s", taints, nodeName)
+
+			return true, nil
+		}
+
+		// resourceVersion in patch is a precondition,
+		// API server returns conflict if node was changed after reading
+		patch, err := json.Marshal([]jsonPatchOperation{
+			{Op: "replace", Path: "/metadata/resourceVersion", Value: node.ResourceVersion},
+			{Op: "add", Path: "/spec/taints", Value: taints},
+		})
+		if err != nil {
+			return false, errors.Wrap(err, "error in json.Marshal")
+		}
+
+		_, err = client.GetKubernetesClient().CoreV1().Nodes().Patch(ctx, nodeName, k8stypes.JSONPatchType, patch, metav1.PatchOptions{
+			FieldManager: fieldManager,
+		})
 
 		switch {
 		case err == nil:
 			return true, nil
 		case apierrorrs.IsConflict(err):
 			return false, nil
-		case err != nil:
-			return false, errors.Wrapf(err, "failed to taint node %s with key %s", freshNode.Name, taintKey)
+		default:
+			return false, errors.Wrap(err, "failed to patch node taints")
 		}
+	})
+}
 
-		return false, nil
+func GetNode(ctx context.Context, nodeName string) (*corev1.Node, error) {
+	node, err := client.GetKubernetesClient().CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error in nodes.get")
+	}
+
+	return node, nil
+}
+
+// SetNodeCondition sets node condition with scheduled event details.
+func SetNodeCondition(ctx context.Context, nodeName string, event types.ScheduledEventsEvent) error {
+	now := metav1.Now()
+
+	return patchNodeCondition(ctx, nodeName, corev1.NodeCondition{
+		Type:               corev1.NodeConditionType(*config.Get().NodeConditionType),
+		Status:             corev1.ConditionTrue,
+		Reason:             string(event.EventType),
+		Message:            fmt.Sprintf(config.NodeConditionMessage, event.EventId, event.NotBeforeString(), event.EventStatus),
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+	})
+}
+
+// ClearNodeCondition marks node condition as resolved when node has no scheduled events.
+func ClearNodeCondition(ctx context.Context, nodeName string) error {
+	now := metav1.Now()
+
+	return patchNodeCondition(ctx, nodeName, corev1.NodeCondition{
+		Type:               corev1.NodeConditionType(*config.Get().NodeConditionType),
+		Status:             corev1.ConditionFalse,
+		Reason:             "NoScheduledEvent",
+		Message:            config.NodeConditionNoEvents,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
 	})
+}
 
-	if updateErr != nil {
-		return err
+// HasPendingEvent returns true if node has changes from scheduled event that was not cleared,
+// for example when handler was restarted while node had scheduled event.
+func HasPendingEvent(ctx context.Context, nodeName string) (bool, error) {
+	node, err := GetNode(ctx, nodeName)
+	if err != nil {
+		return false, errors.Wrap(err, "error in GetNode")
 	}
 
-	log.Warnf("Successfully added taint %s on node %s", taintKey, freshNode.Name)
+	if _, ok := node.Annotations[drainedAnnotation]; ok {
+		return true, nil
+	}
 
-	return nil
+	for _, condition := range node.Status.Conditions {
+		if string(condition.Type) == *config.Get().NodeConditionType && condition.Status == corev1.ConditionTrue {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
-func updateNodeWith(ctx context.Context, taintKey string, taintValue string, node *corev1.Node) error {
+func patchNodeCondition(ctx context.Context, nodeName string, condition corev1.NodeCondition) error {
 	if *config.Get().DryRun {
-		log.Infof("DRY RUN ENABLED; skipping adding taint %s=%s on node %s", taintKey, taintValue, node.Name)
+		log.Infof("DRY RUN ENABLED; skipping condition %s=%s on node %s", condition.Type, condition.Status, nodeName)
+
 		return nil
 	}
-	node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
-		Key:    taintKey,
-		Value:  taintValue,
-		Effect: corev1.TaintEffect(*config.Get().TaintEffect),
-	})
-	_, err := client.GetKubernetesClient().CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
 
-	return errors.Wrap(err, "failed to update node with taint")
-}
+	log.Infof("Setting condition %s=%s on node %s", condition.Type, condition.Status, nodeName)
 
-func GetNode(ctx context.Context, nodeName string) (*corev1.Node, error) {
-	node, err := client.GetKubernetesClient().CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	node, err := GetNode(ctx, nodeName)
 	if err != nil {
-		return nil, errors.Wrap(err, "error in nodes.get")
+		return errors.Wrap(err, "error in GetNode")
 	}
 
-	return node, nil
+	// transition time is changed only when status is changed
+	for _, nodeCondition := range node.Status.Conditions {
+		if nodeCondition.Type == condition.Type && nodeCondition.Status == condition.Status {
+			condition.LastTransitionTime = nodeCondition.LastTransitionTime
+		}
+	}
+
+	// conditions are merged by type with strategic merge patch
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []corev1.NodeCondition{condition},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error in json.Marshal")
+	}
+
+	_, err = client.GetKubernetesClient().CoreV1().Nodes().PatchStatus(ctx, nodeName, patch)
+	if err != nil {
+		return errors.Wrap(err, "error in nodes.patchStatus")
+	}
+
+	return nil
 }
 
 func AddNodeEvent(ctx context.Context, eventType, eventReason, eventMessage string) error {
@@ -194,24 +682,77 @@ func AddNodeEvent(ctx context.Context, eventType, eventReason, eventMessage stri
 }
 
 func AddNodeEventMessage(ctx context.Context, message *types.EventMessage) error {
+	ctx, span := tracing.Start(ctx, "api.AddNodeEvent", trace.WithAttributes(attribute.String("event.reason", message.Reason)))
+
+	err := addNodeEventMessage(ctx, message)
+
+	tracing.End(span, err)
+
+	return err
+}
+
+func addNodeEventMessage(ctx context.Context, message *types.EventMessage) error {
 	node, err := GetNode(ctx, *config.Get().NodeName)
 	if err != nil {
 		return errors.Wrap(err, "error in GetNode")
 	}
 
-	event := corev1.Event{
-		InvolvedObject: corev1.ObjectReference{
+	// nodes are cluster scoped, so involved object has no namespace
+	event := newEvent(message, corev1.ObjectReference{
+		APIVersion:      "v1",
+		Kind:            "Node",
+		Name:            node.Name,
+		UID:             node.UID,
+		ResourceVersion: node.ResourceVersion,
+	})
+
+	return createEvent(ctx, *config.Get().EventsNamespace, event)
+}
+
+// AddPodsEventMessage creates event on every pod that will be evicted from node.
+func AddPodsEventMessage(ctx context.Context, nodeName string, message *types.EventMessage) error {
+	if *config.Get().DryRun {
+		log.Infof("DRY RUN ENABLED; skipping events on pods of node %s", nodeName)
+
+		return nil
+	}
+
+	pods, err := ListNodePods(ctx, nodeName)
+	if err != nil {
+		return errors.Wrap(err, "error in ListNodePods")
+	}
+
+	for _, pod := range pods {
+		// DaemonSet pods are not evicted from node
+		if getPodReferenceKind(pod) == "DaemonSet" {
+			continue
+		}
+
+		event := newEvent(message, corev1.ObjectReference{
 			APIVersion:      "v1",
-			Kind:            "Node",
-			Name:            node.Name,
-			UID:             node.UID,
-			ResourceVersion: node.ResourceVersion,
-		},
+			Kind:            "Pod",
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		})
+
+		if err := createEvent(ctx, pod.Namespace, event); err != nil {
+			log.WithError(err).Errorf("error creating event for pod %s/%s", pod.Namespace, pod.Name)
+		}
+	}
+
+	return nil
+}
+
+func newEvent(message *types.EventMessage, involvedObject corev1.ObjectReference) *corev1.Event {
+	return &corev1.Event{
+		InvolvedObject: involvedObject,
 		Count:          1,
 		FirstTimestamp: metav1.Now(),
 		LastTimestamp:  metav1.Now(),
 		ObjectMeta: metav1.ObjectMeta{
-			Name: fmt.Sprintf("%s.%s", *config.Get().NodeName, uuid.New().String()),
+			Name: fmt.Sprintf("%s.%s", involvedObject.Name, uuid.New().String()),
 		},
 		Type:    message.Type,
 		Reason:  message.Reason,
@@ -220,9 +761,13 @@ func AddNodeEventMessage(ctx context.Context, message *types.EventMessage) error
 			Component: "aks-node-termination-handler",
 		},
 	}
+}
 
-	err = wait.ExponentialBackoff(retry.DefaultBackoff, func() (bool, error) {
-		_, err = client.GetKubernetesClient().CoreV1().Events("default").Create(ctx, &event, metav1.CreateOptions{})
+func createEvent(ctx context.Context, namespace string, event *corev1.Event) error {
+	event.Namespace = namespace
+
+	err := wait.ExponentialBackoff(retry.DefaultBackoff, func() (bool, error) {
+		_, err := client.GetKubernetesClient().CoreV1().Events(namespace).Create(ctx, event, metav1.CreateOptions{})
 
 		switch {
 		case err == nil:
@@ -272,21 +817,39 @@ func GetNodePods(ctx context.Context, nodeName string) ([]string, error) {
 		return []string{}, nil
 	}
 
-	pods, err := client.GetKubernetesClient().CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	pods, err := ListNodePods(ctx, nodeName)
 	if err != nil {
-		return nil, errors.Wrap(err, "error in pods.list")
+		return nil, errors.Wrap(err, "error in ListNodePods")
 	}
 
 	result := make([]string, 0)
 
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
 		// ignore DaemonSet pods from pods list, because they are not affected by node termination
 		if getPodReferenceKind(pod) == "DaemonSet" {
 			continue
 		}
 
+		result = append(result, pod.Name)
+	}
+
+	return result, nil
+}
+
+func ListNodePods(ctx context.Context, nodeName string) ([]corev1.Pod, error) {
+	pods, err := client.GetKubernetesClient().CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error in pods.list")
+	}
+
+	// field selector is not supported by fake clientset in tests
+	result := make([]corev1.Pod, 0, len(pods.Items))
+
+	for _, pod := range pods.Items {
 		if pod.Spec.NodeName == nodeName {
-			result = append(result, pod.Name)
+			result = append(result, pod)
 		}
 	}
 
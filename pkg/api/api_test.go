@@ -0,0 +1,408 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/api"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/client"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/metrics"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newPod(namespace, name, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+//nolint:paralleltest
+func TestAddPodsEventMessage(t *testing.T) {
+	message := &types.EventMessage{Type: "Warning", Reason: "Preempt", Message: "test"}
+
+	for _, dryRun := range []bool{false, true} {
+		clientset := fake.NewClientset(
+			newPod("default", "pod1", "node1"),
+			newPod("kube-system", "pod2", "node1"),
+			newPod("default", "pod3", "node2"),
+		)
+		client.SetKubernetesClient(clientset)
+
+		config.Set(config.Type{DryRun: &dryRun})
+
+		require.NoError(t, api.AddPodsEventMessage(context.TODO(), "node1", message))
+
+		events, err := clientset.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{})
+		require.NoError(t, err)
+
+		if dryRun {
+			assert.Empty(t, events.Items)
+
+			continue
+		}
+
+		involvedPods := make([]string, 0)
+		for _, event := range events.Items {
+			assert.Equal(t, event.InvolvedObject.Namespace, event.Namespace)
+			assert.Equal(t, "Preempt", event.Reason)
+
+			involvedPods = append(involvedPods, event.InvolvedObject.Namespace+"/"+event.InvolvedObject.Name)
+		}
+
+		assert.ElementsMatch(t, []string{"default/pod1", "kube-system/pod2"}, involvedPods)
+	}
+
+	client.SetKubernetesClient(nil)
+}
+
+//nolint:paralleltest
+func TestNodeCondition(t *testing.T) {
+	conditionType := "AzureScheduledEvent"
+	lastTransitionTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	clientset := fake.NewClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{
+				Type:               corev1.NodeConditionType(conditionType),
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: lastTransitionTime,
+			}},
+		},
+	})
+	client.SetKubernetesClient(clientset)
+	defer client.SetKubernetesClient(nil)
+
+	dryRun := true
+
+	config.Set(config.Type{NodeConditionType: &conditionType, DryRun: &dryRun})
+
+	getCondition := func() corev1.NodeCondition {
+		t.Helper()
+
+		node, err := clientset.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Len(t, node.Status.Conditions, 1)
+
+		return node.Status.Conditions[0]
+	}
+
+	event := types.ScheduledEventsEvent{
+		EventId:     "id1",
+		EventType:   types.EventTypePreempt,
+		EventStatus: types.EventStatusScheduled,
+	}
+
+	// node status is not changed in dry run
+	require.NoError(t, api.SetNodeCondition(context.TODO(), "node1", event))
+	assert.Equal(t, corev1.ConditionFalse, getCondition().Status)
+
+	dryRun = false
+
+	hasPendingEvent, err := api.HasPendingEvent(context.TODO(), "node1")
+	require.NoError(t, err)
+	assert.False(t, hasPendingEvent)
+
+	// status is not changed on restart of handler
+	require.NoError(t, api.ClearNodeCondition(context.TODO(), "node1"))
+	assert.Equal(t, lastTransitionTime.Unix(), getCondition().LastTransitionTime.Unix())
+
+	require.NoError(t, api.SetNodeCondition(context.TODO(), "node1", event))
+
+	hasPendingEvent, err = api.HasPendingEvent(context.TODO(), "node1")
+	require.NoError(t, err)
+	assert.True(t, hasPendingEvent)
+
+	condition := getCondition()
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.NotEqual(t, lastTransitionTime.Unix(), condition.LastTransitionTime.Unix())
+
+	// changed status of event is shown in message, transition time is kept
+	event.EventStatus = types.EventStatusStarted
+
+	require.NoError(t, api.SetNodeCondition(context.TODO(), "node1", event))
+	assert.Contains(t, getCondition().Message, "Status=Started")
+	assert.Equal(t, condition.LastTransitionTime.Unix(), getCondition().LastTransitionTime.Unix())
+}
+
+//nolint:paralleltest
+func TestNodeDraining(t *testing.T) {
+	dryRun := false
+
+	config.Set(config.Type{DryRun: &dryRun})
+
+	// node that is already cordoned is not drained again
+	clientset := fake.NewClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	})
+
+	release := make(chan struct{})
+
+	clientset.PrependReactor("get", "nodes", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		<-release
+
+		return false, nil, nil
+	})
+
+	client.SetKubernetesClient(clientset)
+
+	var wg sync.WaitGroup
+
+	for range 2 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := api.DrainNode(context.TODO(), "node1", "Preempt", "event1")
+			assert.NoError(t, err)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.NodeDraining) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	assert.InDelta(t, 0, testutil.ToFloat64(metrics.NodeDraining), 0)
+}
+
+//nolint:paralleltest,funlen
+func TestTaints(t *testing.T) {
+	dryRun := false
+
+	config.Set(config.Type{DryRun: &dryRun})
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", ResourceVersion: "1"},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{{Key: "other", Value: "value", Effect: corev1.TaintEffectNoSchedule}},
+		},
+	}
+
+	clientset := fake.NewClientset(node)
+	client.SetKubernetesClient(clientset)
+
+	defer client.SetKubernetesClient(nil)
+
+	// patch requests including rejected requests
+	patches := func() int {
+		count := 0
+
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "patch" {
+				count++
+			}
+		}
+
+		return count
+	}
+
+	getTaints := func() []corev1.Taint {
+		t.Helper()
+
+		node, err := clientset.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+		require.NoError(t, err)
+
+		return node.Spec.Taints
+	}
+
+	taint := corev1.Taint{Key: "aks-node-termination-handler/preempt", Value: "event1", Effect: corev1.TaintEffectNoSchedule}
+
+	// adding the same taint again does not patch node
+	require.NoError(t, api.AddTaint(context.TODO(), node, taint))
+	require.NoError(t, api.AddTaint(context.TODO(), node, taint))
+	assert.Equal(t, 1, patches())
+	assert.Equal(t, []corev1.Taint{node.Spec.Taints[0], taint}, getTaints())
+
+	// value of taint with the same key and effect is replaced
+	taint.Value = "event2"
+
+	require.NoError(t, api.AddTaint(context.TODO(), node, taint))
+	assert.Equal(t, 2, patches())
+	assert.Equal(t, []corev1.Taint{node.Spec.Taints[0], taint}, getTaints())
+
+	// patch is retried on conflict
+	conflicts := 0
+
+	clientset.PrependReactor("patch", "nodes", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			return false, nil, nil
+		}
+
+		conflicts++
+
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "nodes"}, "node1", nil)
+	})
+
+	require.NoError(t, api.RemoveTaint(context.TODO(), "node1", taint.Key))
+	assert.Equal(t, 1, conflicts)
+	assert.Equal(t, 4, patches())
+	assert.Equal(t, []corev1.Taint{node.Spec.Taints[0]}, getTaints())
+
+	// removing missing taint does not patch node
+	require.NoError(t, api.RemoveTaint(context.TODO(), "node1", taint.Key))
+	assert.Equal(t, 4, patches())
+}
+
+//nolint:paralleltest,funlen
+func TestUncordonNode(t *testing.T) {
+	dryRun := false
+	taintNode := true
+	taintEffect := string(corev1.TaintEffectNoSchedule)
+	taintAutoscaler := true
+	nodeAnnotations := "example.com/draining=true"
+	podGracePeriodSeconds := -1
+	nodeGracePeriodSeconds := 10
+	disableEviction := false
+
+	config.Set(config.Type{
+		DryRun:                 &dryRun,
+		TaintNode:              &taintNode,
+		TaintEffect:            &taintEffect,
+		TaintAutoscaler:        &taintAutoscaler,
+		NodeAnnotations:        &nodeAnnotations,
+		PodGracePeriodSeconds:  &podGracePeriodSeconds,
+		NodeGracePeriodSeconds: &nodeGracePeriodSeconds,
+		DisableEviction:        &disableEviction,
+	})
+
+	clientset := fake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		// node was cordoned by administrator before event
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+	)
+	client.SetKubernetesClient(clientset)
+
+	defer client.SetKubernetesClient(nil)
+
+	getNode := func(nodeName string) *corev1.Node {
+		t.Helper()
+
+		node, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		require.NoError(t, err)
+
+		return node
+	}
+
+	for _, nodeName := range []string{"node1", "node2"} {
+		_, err := api.DrainNode(context.TODO(), nodeName, "Reboot", "event1")
+		require.NoError(t, err)
+	}
+
+	node := getNode("node1")
+	assert.True(t, node.Spec.Unschedulable)
+	assert.Equal(t, "event1", node.Annotations["aks-node-termination-handler/drained"])
+	assert.Equal(t, "true", node.Annotations["example.com/draining"])
+	assert.Len(t, node.Spec.Taints, 2)
+
+	assert.NotContains(t, getNode("node2").Annotations, "aks-node-termination-handler/drained")
+
+	for _, nodeName := range []string{"node1", "node2"} {
+		require.NoError(t, api.UncordonNode(context.TODO(), nodeName))
+	}
+
+	// changes of handler are reverted
+	node = getNode("node1")
+	assert.False(t, node.Spec.Unschedulable)
+	assert.Empty(t, node.Annotations)
+	assert.Empty(t, node.Spec.Taints)
+
+	// node that was cordoned by administrator stays cordoned
+	assert.True(t, getNode("node2").Spec.Unschedulable)
+}
+
+//nolint:paralleltest,funlen
+func TestDeleteNode(t *testing.T) {
+	dryRun := false
+
+	const providerID = "azure:///subscriptions/1/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-vmss/virtualMachines/1"
+
+	testCases := []struct {
+		name          string
+		providerID    string
+		resourceName  string
+		azureResource string
+		deleted       bool
+	}{
+		{name: "match", providerID: providerID, azureResource: "aks-vmss_1", deleted: true},
+		{name: "mismatch", providerID: providerID, azureResource: "aks-vmss_2", deleted: false},
+		// resource name is defined by user
+		{name: "resourceName", providerID: providerID, resourceName: "custom", azureResource: "custom", deleted: true},
+		// resource name is from instance metadata
+		{name: "instanceMetadata", providerID: "unknown://node1", azureResource: "vm1", deleted: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config.Set(config.Type{DryRun: &dryRun, ResourceName: &testCase.resourceName})
+
+			clientset := fake.NewClientset(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node1", UID: "uid1"},
+				Spec:       corev1.NodeSpec{ProviderID: testCase.providerID},
+			})
+			client.SetKubernetesClient(clientset)
+
+			defer client.SetKubernetesClient(nil)
+
+			err := api.DeleteNode(context.TODO(), "node1", testCase.azureResource)
+
+			_, getErr := clientset.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+
+			if !testCase.deleted {
+				require.Error(t, err)
+				require.NoError(t, getErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, apierrors.IsNotFound(getErr))
+
+			// node is deleted only if it was not replaced after reading
+			deleteActions := 0
+
+			for _, action := range clientset.Actions() {
+				if deleteAction, ok := action.(k8stesting.DeleteActionImpl); ok {
+					deleteActions++
+
+					preconditions := deleteAction.GetDeleteOptions().Preconditions
+					require.NotNil(t, preconditions)
+					require.Equal(t, k8stypes.UID("uid1"), *preconditions.UID)
+				}
+			}
+
+			require.Equal(t, 1, deleteActions)
+		})
+	}
+}
@@ -24,7 +24,7 @@ import (
 )
 
 var (
-	clientset  *kubernetes.Clientset
+	clientset  kubernetes.Interface
 	restconfig *rest.Config
 )
 
@@ -50,14 +50,21 @@ func Init() error {
 		}
 	}
 
-	clientset, err = kubernetes.NewForConfig(restconfig)
+	newClientset, err := kubernetes.NewForConfig(restconfig)
 	if err != nil {
 		log.WithError(err).Fatal()
 	}
 
+	clientset = newClientset
+
 	return nil
 }
 
-func GetKubernetesClient() *kubernetes.Clientset {
+func GetKubernetesClient() kubernetes.Interface {
 	return clientset
 }
+
+// SetKubernetesClient replaces client, it's used in tests with fake clientset.
+func SetKubernetesClient(newClientset kubernetes.Interface) {
+	clientset = newClientset
+}
@@ -17,6 +17,8 @@ import (
 	"flag"
 	"os"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
@@ -26,52 +28,104 @@ import (
 )
 
 const (
-	azureEndpoint                 = "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01"
+	azureEndpoint                 = "http://169.254.169.254/metadata/scheduledevents"
+	defaultAPIVersion             = "auto"
+	instanceEndpoint              = "http://169.254.169.254/metadata/instance/compute?api-version=2021-02-01"
 	defaultAlertMessage           = "Draining node={{ .NodeName }}, type={{ .Event.EventType }}"
 	defaultPeriod                 = 5 * time.Second
+	defaultScheduledPeriod        = defaultPeriod
+	defaultMaxBackoff             = 60 * time.Second
+	defaultFailureThreshold       = 5
 	defaultPodGracePeriodSeconds  = -1
 	defaultNodeGracePeriodSeconds = 120
 	defaultGracePeriodSecond      = 10
 	defaultRequestTimeout         = 5 * time.Second
 	defaultWebHookTimeout         = 30 * time.Second
+	defaultPodHooksTimeout        = 60 * time.Second
+	defaultExecHooksTimeout       = 60 * time.Second
+	defaultConfigReloadPeriod     = 10 * time.Second
 	defaultDryRun                 = false
+	defaultEventsNamespace        = "default"
+	defaultNodeConditionType      = "AzureScheduledEvent"
+)
+
+const (
+	DrainAuthKubernetes = "kubernetes"
+	DrainAuthToken      = "token"
+	DrainAuthNone       = "none"
+
+	DrainConcurrencyScopeCluster = "cluster"
+	DrainConcurrencyScopePool    = "pool"
 )
 
 const (
 	EventMessageReceived     = "Azure API sended schedule event for this node"
 	EventMessageBeforeListen = "Start to listen events from Azure API"
+	EventMessagePodEviction  = "Pod will be evicted from node %s, Azure API sended %s event (EventId=%s, NotBefore=%s)"
+	NodeConditionMessage     = "EventId=%s, NotBefore=%s, Status=%s"
+	NodeConditionNoEvents    = "No scheduled events from Azure API"
+	EventMessageReadFailed   = "Failed to read scheduled events %d times in a row: %s"
+	EventMessageManualDrain  = "Manual drain requested by %s (EventType=%s, DryRun=%t)"
+	EventMessageDeleteNode   = "Azure API started event, node will be deleted"
+	EventMessageSimulated    = "[SIMULATED] "
+	EventMessageLeaseWaiting = "Waiting for drain lease %s until %s"
+	EventMessageLeaseAcquire = "Drain lease %s acquired"
+	EventMessageLeaseTimeout = "Drain lease %s is not acquired, draining without lease"
 )
 
 var (
 	errNoNode             = errors.New("no node name is defined, run with -node=test")
 	errChatIDMustBeInt    = errors.New("TelegramChatID must be integer")
 	errInvalidTaintEffect = errors.New("TaintEffect must be either NoSchedule, NoExecute or PreferNoSchedule")
+	errInvalidAnnotations = errors.New("NodeAnnotations must be in format key=value,key2=value2")
+	errInvalidDeleteNode  = errors.New("DeleteNodeEvents must contain only Preempt or Terminate events")
+	errInvalidScope       = errors.New("DrainConcurrencyScope must be either cluster or pool")
+	errNoLeaseNamespace   = errors.New("DrainLeaseNamespace must be defined when DrainConcurrency is enabled")
+	errInvalidDrainAuth   = errors.New("DrainAuth must be kubernetes, token or none")
+	errNoDrainToken       = errors.New("DrainToken must be defined when DrainAuth is token")
+	errInvalidWebTLS      = errors.New("WebTLSCert and WebTLSKey must be defined together")
+	errInvalidPeriod      = errors.New("Period and MaxBackoff must be greater than zero")
+	errInvalidExecHooks   = errors.New("ExecHooks must be YAML list of commands with arguments, for example [[\"/bin/hook\", \"--event\"]]")
 )
 
 type Type struct {
 	ConfigFile             *string
+	ConfigReloadPeriod     *time.Duration
 	LogPretty              *bool
 	LogLevel               *string
 	DryRun                 *bool
 	KubeConfigFile         *string
 	Endpoint               *string
+	APIVersion             *string
 	NodeName               *string
 	Period                 *time.Duration
+	ScheduledPeriod        *time.Duration
+	MaxBackoff             *time.Duration
+	FailureThreshold       *int
 	RequestTimeout         *time.Duration
-	TelegramToken          *string
+	TelegramToken          *string `secret:"true"`
 	TelegramChatID         *string
 	AlertMessage           *string
 	WebHookContentType     *string
-	WebHookURL             *string
+	WebHookURL             *string `secret:"url"`
 	WebHookTemplate        *string
 	WebHookTemplateFile    *string
 	WebHookMethod          *string
 	WebHookTimeout         *time.Duration
 	WebhookInsecure        *bool
-	WebhookProxy           *string
+	WebhookProxy           *string `secret:"url"`
 	WebhookRetries         *int
-	SentryDSN              *string
+	SentryDSN              *string `secret:"url"`
 	WebHTTPAddress         *string
+	WebAdminAddress        *string
+	WebTLSCert             *string
+	WebTLSKey              *string
+	WebPprof               *bool
+	WebDrain               *bool
+	TracingEndpoint        *string
+	TracingSampleRatio     *float64
+	DrainAuth              *string
+	DrainToken             *string `secret:"true"`
 	TaintNode              *bool
 	TaintEffect            *string
 	PodGracePeriodSeconds  *int
@@ -81,16 +135,45 @@ type Type struct {
 	ResourceName           *string
 	ExitAfterNodeDrain     *bool
 	DisableEviction        *bool
+	EventsNamespace        *string
+	PodEvents              *bool
+	NodeConditionType      *string
+	NodeAnnotations        *string
+	TaintAutoscaler        *bool
+	UncordonAfterEvent     *bool
+	DeleteNodeEvents       *string
+	DrainConcurrency       *int
+	DrainConcurrencyScope  *string
+	DrainLeaseNamespace    *string
+	PodHooksTimeout        *time.Duration
+	Standalone             *bool
+	InstanceEndpoint       *string
+	ExecHooks              *string
+	ExecHooksTimeout       *time.Duration
 }
 
+var (
+	// current config, it's replaced as a whole when config file is reloaded
+	current atomic.Pointer[Type]
+	// config from flags, before config file was applied
+	flagsConfig *Type
+	// config after config file was applied on start
+	loadedConfig *Type
+)
+
 var config = Type{
 	ConfigFile:             flag.String("config", os.Getenv("CONFIG"), "config file"),
+	ConfigReloadPeriod:     flag.Duration("config.reloadPeriod", defaultConfigReloadPeriod, "interval of checking config file for changes, 0 disables reload"),
 	LogLevel:               flag.String("log.level", "INFO", "log level"),
 	LogPretty:              flag.Bool("log.pretty", false, "log in text"),
 	KubeConfigFile:         flag.String("kubeconfig", "", "kubeconfig file"),
 	Endpoint:               flag.String("endpoint", azureEndpoint, "scheduled-events endpoint"),
+	APIVersion:             flag.String("api-version", defaultAPIVersion, "scheduled-events api version, auto detects newest supported version, ignored if endpoint has api-version"),
 	NodeName:               flag.String("node", os.Getenv("MY_NODE_NAME"), "node to drain"),
 	Period:                 flag.Duration("period", defaultPeriod, "period to scrape endpoint"),
+	ScheduledPeriod:        flag.Duration("period.scheduled", defaultScheduledPeriod, "period to scrape endpoint while node has scheduled events"),
+	MaxBackoff:             flag.Duration("period.maxBackoff", defaultMaxBackoff, "maximum period to scrape endpoint after failures, failures are retried with exponential backoff"),
+	FailureThreshold:       flag.Int("events.failureThreshold", defaultFailureThreshold, "number of consecutive failures to read endpoint after which node event is created"),
 	RequestTimeout:         flag.Duration("request.timeout", defaultRequestTimeout, "request timeout"),
 	TelegramToken:          flag.String("telegram.token", os.Getenv("TELEGRAM_TOKEN"), "telegram token"),
 	TelegramChatID:         flag.String("telegram.chatID", os.Getenv("TELEGRAM_CHATID"), "telegram chatID"),
@@ -106,6 +189,15 @@ var config = Type{
 	WebhookRetries:         flag.Int("webhook.retries", 3, "number of retries for webhook"), //nolint:mnd
 	SentryDSN:              flag.String("sentry.dsn", "", "sentry DSN"),
 	WebHTTPAddress:         flag.String("web.address", ":17923", ""),
+	WebAdminAddress:        flag.String("web.adminAddress", "", "separate address of admin endpoints (/drainNode, /simulate, /status, /events, /debug/pprof), by default they are served on web.address"),
+	WebTLSCert:             flag.String("web.tls.cert", "", "path to TLS certificate, web server uses TLS when certificate and key are defined, files are reloaded when changed"),
+	WebTLSKey:              flag.String("web.tls.key", "", "path to TLS private key"),
+	WebPprof:               flag.Bool("web.pprof", true, "serve /debug/pprof endpoints"),
+	WebDrain:               flag.Bool("web.drain", true, "serve /drainNode and /simulate endpoints"),
+	TracingEndpoint:        flag.String("tracing.endpoint", os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), "OTLP HTTP endpoint of traces, for example http://otel-collector:4318/v1/traces, empty value disables tracing"),
+	TracingSampleRatio:     flag.Float64("tracing.sampleRatio", 1, "ratio of traces that are sampled"),
+	DrainAuth:              flag.String("web.drainAuth", DrainAuthKubernetes, "authentication of /drainNode requests: kubernetes (TokenReview and SubjectAccessReview), token or none"),
+	DrainToken:             flag.String("web.drainToken", os.Getenv("DRAIN_TOKEN"), "bearer token of /drainNode requests when web.drainAuth is token"),
 	TaintNode:              flag.Bool("taint.node", false, "Taint the node before cordon and draining"),
 	TaintEffect:            flag.String("taint.effect", "NoSchedule", "Taint effect to set on the node"),
 	PodGracePeriodSeconds:  flag.Int("podGracePeriodSeconds", defaultPodGracePeriodSeconds, "grace period is seconds for pods termination"),
@@ -116,6 +208,21 @@ var config = Type{
 	ExitAfterNodeDrain:     flag.Bool("exitAfterNodeDrain", false, "process will exit after node drain"),
 	DisableEviction:        flag.Bool("disableEviction", false, "if true, force drain to use delete, even if eviction is supported. This will bypass checking PodDisruptionBudgets"),
 	DryRun:                 flag.Bool("dryRun", defaultDryRun, "if true, nodes will not be tainted, cordoned, or drained"),
+	EventsNamespace:        flag.String("events.namespace", defaultEventsNamespace, "namespace to create node events in"),
+	PodEvents:              flag.Bool("events.pods", true, "create events on pods that will be evicted from node"),
+	NodeConditionType:      flag.String("node.conditionType", defaultNodeConditionType, "node condition type to describe scheduled event, empty value disables condition"),
+	NodeAnnotations:        flag.String("node.annotations", "", "comma separated annotations to add on node before draining, for example cluster-autoscaler.kubernetes.io/scale-down-disabled=true"),
+	TaintAutoscaler:        flag.Bool("taint.autoscaler", false, "add ToBeDeletedByClusterAutoscaler taint on node before draining"),
+	UncordonAfterEvent:     flag.Bool("uncordonAfterEvent", false, "uncordon node and revert taints and annotations when scheduled events are gone"),
+	DeleteNodeEvents:       flag.String("deleteNode.events", "", "comma separated event types (Preempt,Terminate) after which node will be deleted, when event is started"),
+	DrainConcurrency:       flag.Int("drain.concurrency", 0, "maximum number of nodes that drains at the same time for non urgent events, 0 disables limit"),
+	DrainConcurrencyScope:  flag.String("drain.concurrency.scope", DrainConcurrencyScopeCluster, "scope of drain concurrency limit, cluster or pool"),
+	DrainLeaseNamespace:    flag.String("drain.concurrency.namespace", os.Getenv("POD_NAMESPACE"), "namespace of leases for drain concurrency limit"),
+	PodHooksTimeout:        flag.Duration("hooks.timeout", defaultPodHooksTimeout, "maximum time to wait for pods hooks before draining node, 0 disables hooks"),
+	Standalone:             flag.Bool("standalone", false, "run without Kubernetes on plain virtual machine, only notifications and exec hooks are used"),
+	InstanceEndpoint:       flag.String("endpoint.instance", instanceEndpoint, "instance metadata endpoint"),
+	ExecHooks:              flag.String("hooks.exec", "", "YAML list of commands with arguments to run on event in standalone mode, for example [[\"/bin/hook\", \"--event\"]]"),
+	ExecHooksTimeout:       flag.Duration("hooks.exec.timeout", defaultExecHooksTimeout, "timeout of every exec hook"),
 }
 
 func (t *Type) GracePeriod() time.Duration {
@@ -135,58 +242,218 @@ func (t *Type) IsExcludedEvent(e types.ScheduledEventsEventType) bool {
 	return false
 }
 
+// check if node must be deleted after event is started.
+func (t *Type) IsDeleteNodeEvent(e types.ScheduledEventsEventType) bool {
+	for _, eventType := range t.deleteNodeEvents() {
+		if strings.EqualFold(eventType, string(e)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Type) deleteNodeEvents() []string {
+	result := make([]string, 0)
+
+	if t.DeleteNodeEvents == nil {
+		return result
+	}
+
+	for _, eventType := range strings.Split(*t.DeleteNodeEvents, ",") {
+		if eventType = strings.TrimSpace(eventType); len(eventType) > 0 {
+			result = append(result, eventType)
+		}
+	}
+
+	return result
+}
+
+// NodeAnnotationsMap returns annotations that will be added on node before draining.
+func (t *Type) NodeAnnotationsMap() (map[string]string, error) {
+	result := make(map[string]string)
+
+	if t.NodeAnnotations == nil {
+		return result, nil
+	}
+
+	for _, annotation := range strings.Split(*t.NodeAnnotations, ",") {
+		if len(strings.TrimSpace(annotation)) == 0 {
+			continue
+		}
+
+		key, value, found := strings.Cut(annotation, "=")
+		if !found || len(strings.TrimSpace(key)) == 0 {
+			return nil, errInvalidAnnotations
+		}
+
+		result[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return result, nil
+}
+
+// String returns config in JSON, secrets are redacted.
 func (t *Type) String() string {
-	b, err := json.Marshal(t)
-	if err != nil {
+	var b strings.Builder
+
+	encoder := json.NewEncoder(&b)
+	// redacted values are not escaped
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(t.redacted()); err != nil {
 		return err.Error()
 	}
 
-	return string(b)
+	return strings.TrimSpace(b.String())
+}
+
+// ExecHooksList returns commands that will be run on event,
+// commands are YAML list of arguments lists, for example [["/bin/hook", "--event"]].
+func (t *Type) ExecHooksList() ([][]string, error) {
+	result := make([][]string, 0)
+
+	if t.ExecHooks == nil || len(strings.TrimSpace(*t.ExecHooks)) == 0 {
+		return result, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(*t.ExecHooks), &result); err != nil {
+		return nil, errors.Wrap(errInvalidExecHooks, err.Error())
+	}
+
+	for _, args := range result {
+		if len(args) == 0 || len(args[0]) == 0 {
+			return nil, errInvalidExecHooks
+		}
+	}
+
+	return result, nil
+}
+
+// IsWebTLS returns true if web server uses TLS.
+func (t *Type) IsWebTLS() bool {
+	return t.WebTLSCert != nil && len(*t.WebTLSCert) > 0
 }
 
+// IsWebPprof returns true if /debug/pprof endpoints are served, enabled by default.
+func (t *Type) IsWebPprof() bool {
+	return t.WebPprof == nil || *t.WebPprof
+}
+
+// IsWebDrain returns true if /drainNode and /simulate endpoints are served, enabled by default.
+func (t *Type) IsWebDrain() bool {
+	return t.WebDrain == nil || *t.WebDrain
+}
+
+// IsStandalone returns true if handler runs without Kubernetes.
+func (t *Type) IsStandalone() bool {
+	return t.Standalone != nil && *t.Standalone
+}
+
+// Check validates config.
 func Check() error {
-	if len(*config.NodeName) == 0 {
+	return Get().Check()
+}
+
+// Check validates config.
+func (t *Type) Check() error {
+	// node name is optional in standalone mode
+	if len(*t.NodeName) == 0 && !t.IsStandalone() {
 		return errNoNode
 	}
 
-	if len(*config.TelegramChatID) > 0 {
-		if _, err := strconv.Atoi(*config.TelegramChatID); err != nil {
+	if (t.Period != nil && *t.Period <= 0) || (t.MaxBackoff != nil && *t.MaxBackoff <= 0) {
+		return errInvalidPeriod
+	}
+
+	if len(*t.TelegramChatID) > 0 {
+		if _, err := strconv.Atoi(*t.TelegramChatID); err != nil {
 			return errChatIDMustBeInt
 		}
 	}
 
-	taintEffect := *config.TaintEffect
+	taintEffect := *t.TaintEffect
 	if taintEffect != string(corev1.TaintEffectNoSchedule) &&
 		taintEffect != string(corev1.TaintEffectNoExecute) &&
 		taintEffect != string(corev1.TaintEffectPreferNoSchedule) {
 		return errInvalidTaintEffect
 	}
 
+	if _, err := t.NodeAnnotationsMap(); err != nil {
+		return err
+	}
+
+	if _, err := t.ExecHooksList(); err != nil {
+		return err
+	}
+
+	// only events that deletes virtual machine are allowed
+	for _, eventType := range t.deleteNodeEvents() {
+		if !strings.EqualFold(eventType, string(types.EventTypePreempt)) && !strings.EqualFold(eventType, string(types.EventTypeTerminate)) {
+			return errInvalidDeleteNode
+		}
+	}
+
+	if t.DrainAuth != nil {
+		switch *t.DrainAuth {
+		case DrainAuthKubernetes, DrainAuthNone:
+		case DrainAuthToken:
+			if len(*t.DrainToken) == 0 {
+				return errNoDrainToken
+			}
+		default:
+			return errInvalidDrainAuth
+		}
+	}
+
+	if t.WebTLSCert != nil && t.WebTLSKey != nil && (len(*t.WebTLSCert) == 0) != (len(*t.WebTLSKey) == 0) {
+		return errInvalidWebTLS
+	}
+
+	if t.DrainConcurrency != nil && *t.DrainConcurrency > 0 {
+		if *t.DrainConcurrencyScope != DrainConcurrencyScopeCluster && *t.DrainConcurrencyScope != DrainConcurrencyScopePool {
+			return errInvalidScope
+		}
+
+		if len(*t.DrainLeaseNamespace) == 0 {
+			return errNoLeaseNamespace
+		}
+	}
+
 	return nil
 }
 
 func Get() *Type {
+	if t := current.Load(); t != nil {
+		return t
+	}
+
 	return &config
 }
 
 func Set(specifiedConfig Type) {
-	config = specifiedConfig
+	current.Store(&specifiedConfig)
+
+	flagsConfig = nil
+	loadedConfig = nil
+
+	sources.Store(nil)
+	nodeLabels.Store(nil)
 }
 
 func Load() error {
-	if len(*config.ConfigFile) == 0 {
-		return nil
-	}
+	// values of flags are used again on reload
+	flagsConfig = Get().clone()
 
-	configByte, err := os.ReadFile(*config.ConfigFile)
+	newConfig, newSources, err := flagsConfig.load()
 	if err != nil {
-		return errors.Wrap(err, "error in os.ReadFile")
+		return err
 	}
 
-	err = yaml.Unmarshal(configByte, &config)
-	if err != nil {
-		return errors.Wrap(err, "error in yaml.Unmarshal")
-	}
+	loadedConfig = newConfig.clone()
+
+	sources.Store(&newSources)
+	current.Store(newConfig)
 
 	return nil
 }
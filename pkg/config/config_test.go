@@ -13,6 +13,8 @@ limitations under the License.
 package config_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -28,7 +30,8 @@ func TestConfigDefaults(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	assert.Equal(t, "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01", *config.Get().Endpoint)
+	assert.Equal(t, "http://169.254.169.254/metadata/scheduledevents", *config.Get().Endpoint)
+	assert.Equal(t, "auto", *config.Get().APIVersion)
 }
 
 //nolint:paralleltest
@@ -81,10 +84,15 @@ func TestVersion(t *testing.T) {
 
 //nolint:paralleltest,funlen
 func TestConfig(t *testing.T) {
+	zeroDuration := time.Duration(0)
+	validDuration := time.Second
+
 	testCases := []struct {
 		taintEffect string
 		nodeName    string
 		telegramID  string
+		period      *time.Duration
+		maxBackoff  *time.Duration
 		err         bool
 		testName    string
 	}{
@@ -137,11 +145,40 @@ func TestConfig(t *testing.T) {
 			telegramID:  "1",
 			err:         true,
 		},
+		{
+			testName:    "validPeriod",
+			taintEffect: "NoSchedule",
+			nodeName:    "validNode",
+			telegramID:  "1",
+			period:      &validDuration,
+			maxBackoff:  &validDuration,
+			err:         false,
+		},
+		{
+			testName:    "InvalidPeriod",
+			taintEffect: "NoSchedule",
+			nodeName:    "validNode",
+			telegramID:  "1",
+			period:      &zeroDuration,
+			maxBackoff:  &validDuration,
+			err:         true,
+		},
+		{
+			testName:    "InvalidMaxBackoff",
+			taintEffect: "NoSchedule",
+			nodeName:    "validNode",
+			telegramID:  "1",
+			period:      &validDuration,
+			maxBackoff:  &zeroDuration,
+			err:         true,
+		},
 	}
 
 	for i := range testCases {
 		t.Run(testCases[i].testName, func(t *testing.T) {
 			newConfig := config.Type{
+				Period:         testCases[i].period,
+				MaxBackoff:     testCases[i].maxBackoff,
 				TaintEffect:    &testCases[i].taintEffect,
 				NodeName:       &testCases[i].nodeName,
 				TelegramChatID: &testCases[i].telegramID,
@@ -179,3 +216,353 @@ func TestIsExcludedEvent(t *testing.T) {
 		t.Fatal("when DrainOnFreezeEvent is true, IsExcludedEvent must be false")
 	}
 }
+
+func TestNodeAnnotationsMap(t *testing.T) {
+	t.Parallel()
+
+	nodeAnnotations := "cluster-autoscaler.kubernetes.io/scale-down-disabled=true, karpenter.sh/do-not-disrupt=true,"
+
+	testConfig := config.Type{
+		NodeAnnotations: &nodeAnnotations,
+	}
+
+	annotations, err := testConfig.NodeAnnotationsMap()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"cluster-autoscaler.kubernetes.io/scale-down-disabled": "true",
+		"karpenter.sh/do-not-disrupt":                          "true",
+	}, annotations)
+
+	invalidAnnotations := "invalid"
+	testConfig.NodeAnnotations = &invalidAnnotations
+
+	_, err = testConfig.NodeAnnotationsMap()
+	require.Error(t, err)
+}
+
+func TestIsDeleteNodeEvent(t *testing.T) {
+	t.Parallel()
+
+	deleteNodeEvents := "Preempt, terminate"
+
+	testConfig := config.Type{
+		DeleteNodeEvents: &deleteNodeEvents,
+	}
+
+	assert.True(t, testConfig.IsDeleteNodeEvent(types.EventTypePreempt))
+	assert.True(t, testConfig.IsDeleteNodeEvent(types.EventTypeTerminate))
+	assert.False(t, testConfig.IsDeleteNodeEvent(types.EventTypeReboot))
+}
+
+func TestExecHooksList(t *testing.T) {
+	t.Parallel()
+
+	execHooks := `[["/usr/local/bin/hook1"], ["/usr/local/bin/hook2", "--message", "node, is drained"]]`
+
+	testConfig := config.Type{
+		ExecHooks: &execHooks,
+	}
+
+	commands, err := testConfig.ExecHooksList()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"/usr/local/bin/hook1"}, {"/usr/local/bin/hook2", "--message", "node, is drained"}}, commands)
+
+	commands, err = (&config.Type{}).ExecHooksList()
+	require.NoError(t, err)
+	assert.Empty(t, commands)
+
+	for _, invalid := range []string{"/usr/local/bin/hook1", "[[]]", `[["/bin/hook"], "/bin/hook2"]`} {
+		testConfig.ExecHooks = &invalid
+
+		_, err := testConfig.ExecHooksList()
+		require.Error(t, err, invalid)
+	}
+}
+
+//nolint:paralleltest
+func TestWebTLS(t *testing.T) {
+	nodeName := "validNode"
+	taintEffect := "NoSchedule"
+	telegramID := ""
+	certFile := "/tls/tls.crt"
+	keyFile := ""
+
+	newConfig := config.Type{
+		NodeName:       &nodeName,
+		TaintEffect:    &taintEffect,
+		TelegramChatID: &telegramID,
+		WebTLSCert:     &certFile,
+		WebTLSKey:      &keyFile,
+	}
+	config.Set(newConfig)
+
+	require.Error(t, config.Check())
+
+	keyFile = "/tls/tls.key"
+
+	require.NoError(t, config.Check())
+	assert.True(t, config.Get().IsWebTLS())
+	assert.True(t, config.Get().IsWebPprof())
+}
+
+//nolint:paralleltest
+func TestReload(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	writeConfig := func(content string) {
+		t.Helper()
+
+		require.NoError(t, os.WriteFile(configFile, []byte(content), 0o600))
+	}
+
+	writeConfig("nodename: validNode\ntainteffect: NoSchedule\nalertmessage: first\n")
+
+	nodeName := ""
+	taintEffect := ""
+	telegramID := ""
+	alertMessage := ""
+
+	newConfig := config.Type{
+		ConfigFile:     &configFile,
+		NodeName:       &nodeName,
+		TaintEffect:    &taintEffect,
+		TelegramChatID: &telegramID,
+		AlertMessage:   &alertMessage,
+	}
+	config.Set(newConfig)
+
+	require.NoError(t, config.Load())
+	require.NoError(t, config.Check())
+	assert.Equal(t, "first", *config.Get().AlertMessage)
+
+	writeConfig("nodename: validNode\ntainteffect: NoSchedule\nalertmessage: second\n")
+	require.NoError(t, config.Reload())
+	assert.Equal(t, "second", *config.Get().AlertMessage)
+
+	// invalid config is not applied
+	writeConfig("nodename: validNode\ntainteffect: Invalid\nalertmessage: third\n")
+	require.Error(t, config.Reload())
+	assert.Equal(t, "second", *config.Get().AlertMessage)
+
+	// fields that are used only on start can not be changed
+	writeConfig("nodename: otherNode\ntainteffect: NoSchedule\nalertmessage: third\n")
+	require.Error(t, config.Reload())
+	assert.Equal(t, "validNode", *config.Get().NodeName)
+	assert.Equal(t, "second", *config.Get().AlertMessage)
+}
+
+func TestEnvName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "ANTH_TAINT_EFFECT", config.EnvName("taint.effect"))
+	assert.Equal(t, "ANTH_POD_GRACE_PERIOD_SECONDS", config.EnvName("podGracePeriodSeconds"))
+	assert.Equal(t, "ANTH_TELEGRAM_CHAT_ID", config.EnvName("telegram.chatID"))
+	assert.Equal(t, "ANTH_WEBHOOK_TEMPLATE_FILE", config.EnvName("webhook.template-file"))
+}
+
+//nolint:paralleltest
+func TestEnvOverrides(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(configFile, []byte("tainteffect: NoSchedule\npodgraceperiodseconds: 10\ntelegramtoken: secret\n"), 0o600)
+	require.NoError(t, err)
+
+	t.Setenv("ANTH_TAINT_EFFECT", "NoExecute")
+	t.Setenv("ANTH_NODE_GRACE_PERIOD_SECONDS", "60")
+
+	nodeGracePeriod := 120
+	newConfig := config.Type{
+		ConfigFile:             &configFile,
+		NodeGracePeriodSeconds: &nodeGracePeriod,
+	}
+	config.Set(newConfig)
+
+	require.NoError(t, config.Load())
+
+	assert.Equal(t, "NoExecute", *config.Get().TaintEffect)
+	assert.Equal(t, 10, *config.Get().PodGracePeriodSeconds)
+	assert.Equal(t, 60, *config.Get().NodeGracePeriodSeconds)
+
+	options := make(map[string]config.Option)
+	for _, option := range config.Describe() {
+		// every field can be set with flag and environment variable
+		assert.NotEmpty(t, option.Env, option.Field)
+
+		options[option.Field] = option
+	}
+
+	assert.Equal(t, config.SourceEnv, options["TaintEffect"].Source)
+	assert.Equal(t, config.SourceFile, options["PodGracePeriodSeconds"].Source)
+	assert.Equal(t, config.SourceEnv, options["NodeGracePeriodSeconds"].Source)
+	assert.Equal(t, config.SourceDefault, options["LogLevel"].Source)
+	assert.Equal(t, "<redacted>", options["TelegramToken"].Value)
+
+	t.Setenv("ANTH_NODE_GRACE_PERIOD_SECONDS", "invalid")
+	config.Set(newConfig)
+
+	require.Error(t, config.Load())
+}
+
+//nolint:paralleltest
+func TestNodeOverrides(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	writeConfig := func(content string) {
+		t.Helper()
+
+		require.NoError(t, os.WriteFile(configFile, []byte(content), 0o600))
+	}
+
+	writeConfig(`
+tainteffect: NoSchedule
+podgraceperiodseconds: 30
+overrides:
+- name: spot
+  nodeSelector: kubernetes.azure.com/scalesetpriority=spot
+  config:
+    tainteffect: NoExecute
+    podgraceperiodseconds: 10
+- name: windows
+  nodeSelector: kubernetes.io/os=windows
+  config:
+    alertmessage: windows
+`)
+
+	nodeName := "validNode"
+	telegramID := ""
+
+	newConfig := config.Type{
+		ConfigFile:     &configFile,
+		NodeName:       &nodeName,
+		TelegramChatID: &telegramID,
+	}
+	config.Set(newConfig)
+
+	require.NoError(t, config.Load())
+
+	// overrides are not applied until node labels are known
+	assert.Equal(t, "NoSchedule", *config.Get().TaintEffect)
+
+	require.NoError(t, config.SetNodeLabels(map[string]string{
+		"kubernetes.azure.com/scalesetpriority": "spot",
+		"kubernetes.io/os":                      "linux",
+	}))
+
+	assert.Equal(t, "NoExecute", *config.Get().TaintEffect)
+	assert.Equal(t, 10, *config.Get().PodGracePeriodSeconds)
+	assert.Nil(t, config.Get().AlertMessage)
+
+	for _, option := range config.Describe() {
+		if option.Field == "TaintEffect" {
+			assert.Equal(t, config.SourceOverride, option.Source)
+		}
+	}
+
+	// overrides are applied on reload
+	writeConfig(`
+tainteffect: NoSchedule
+overrides:
+- nodeSelector: kubernetes.io/os=linux
+  config:
+    tainteffect: PreferNoSchedule
+`)
+	require.NoError(t, config.Reload())
+	assert.Equal(t, "PreferNoSchedule", *config.Get().TaintEffect)
+
+	// fields that are used only on start can not be overridden
+	writeConfig(`
+tainteffect: NoSchedule
+overrides:
+- nodeSelector: kubernetes.io/os=windows
+  config:
+    nodename: otherNode
+`)
+	require.Error(t, config.Reload())
+
+	writeConfig(`
+tainteffect: NoSchedule
+overrides:
+- nodeSelector: kubernetes.io/os=windows
+  config:
+    unknownfield: value
+`)
+	require.Error(t, config.Reload())
+
+	writeConfig(`
+tainteffect: NoSchedule
+overrides:
+- nodeSelector: "!!invalid"
+  config:
+    tainteffect: NoExecute
+`)
+	require.Error(t, config.Reload())
+	assert.Equal(t, "PreferNoSchedule", *config.Get().TaintEffect)
+}
+
+//nolint:paralleltest
+func TestSecrets(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0o600))
+
+	t.Setenv("ANTH_TELEGRAM_TOKEN_FILE", tokenFile)
+	t.Setenv("ANTH_WEBHOOK_URL", "https://hooks.slack.com/services/T000/B000/XXXX")
+	t.Setenv("ANTH_SENTRY_DSN", "https://key@o1.ingest.sentry.io/1")
+
+	config.Set(config.Type{})
+
+	require.NoError(t, config.Load())
+	assert.Equal(t, "file-token", *config.Get().TelegramToken)
+
+	configString := config.Get().String()
+	assert.NotContains(t, configString, "file-token")
+	assert.NotContains(t, configString, "XXXX")
+	assert.NotContains(t, configString, "key@")
+	assert.Contains(t, configString, "https://hooks.slack.com/<redacted>")
+
+	for _, option := range config.Describe() {
+		if option.Field == "TelegramToken" {
+			assert.Equal(t, config.SourceEnvFile, option.Source)
+			assert.Equal(t, "<redacted>", option.Value)
+		}
+	}
+
+	// value and file can not be defined together
+	t.Setenv("ANTH_TELEGRAM_TOKEN", "env-token")
+	config.Set(config.Type{})
+
+	require.Error(t, config.Load())
+}
+
+//nolint:paralleltest
+func TestLegacySecretFiles(t *testing.T) {
+	webhookFile := filepath.Join(t.TempDir(), "webhook-url")
+	require.NoError(t, os.WriteFile(webhookFile, []byte("https://hooks.slack.com/services/T000/B000/XXXX\n"), 0o600))
+
+	t.Setenv("WEBHOOK_URL_FILE", webhookFile)
+
+	webhookURL := ""
+
+	config.Set(config.Type{WebHookURL: &webhookURL})
+
+	require.NoError(t, config.Load())
+	assert.Equal(t, "https://hooks.slack.com/services/T000/B000/XXXX", *config.Get().WebHookURL)
+
+	for _, option := range config.Describe() {
+		if option.Field == "WebHookURL" {
+			assert.Equal(t, config.SourceEnvFile, option.Source)
+		}
+	}
+
+	// environment variable with ANTH_ prefix overrides legacy file
+	t.Setenv("ANTH_WEBHOOK_URL", "https://example.com/webhook")
+	config.Set(config.Type{WebHookURL: &webhookURL})
+
+	require.NoError(t, config.Load())
+	assert.Equal(t, "https://example.com/webhook", *config.Get().WebHookURL)
+
+	// value and file can not be defined together
+	t.Setenv("WEBHOOK_URL", "https://example.com/legacy")
+	config.Set(config.Type{WebHookURL: &webhookURL})
+
+	require.Error(t, config.Load())
+}
@@ -0,0 +1,129 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"slices"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var (
+	errInvalidOverride = errors.New("invalid override")
+	errUnknownField    = errors.New("unknown field")
+)
+
+// labels of node where handler runs, overrides are applied when labels are known.
+var nodeLabels atomic.Pointer[labels.Set]
+
+// override changes config on nodes that match node selector.
+type override struct {
+	Name string `yaml:"name"`
+	// label selector, for example kubernetes.azure.com/scalesetpriority=spot
+	NodeSelector string    `yaml:"nodeSelector"`
+	Config       yaml.Node `yaml:"config"`
+}
+
+// SetNodeLabels applies overrides that match labels of node, config is validated before it's applied.
+func SetNodeLabels(newLabels map[string]string) error {
+	if flagsConfig == nil || loadedConfig == nil {
+		return errNotLoaded
+	}
+
+	labelSet := labels.Set(newLabels)
+	nodeLabels.Store(&labelSet)
+
+	newConfig, newSources, err := flagsConfig.load()
+	if err != nil {
+		return err
+	}
+
+	if err := newConfig.Check(); err != nil {
+		return errors.Wrap(err, "config with overrides is not valid")
+	}
+
+	// values of fields that are used only on start can be changed after start, for example node name in standalone mode
+	copyFields(Get(), newConfig, immutableFields)
+
+	loadedConfig = newConfig.clone()
+
+	sources.Store(&newSources)
+	current.Store(newConfig)
+
+	return nil
+}
+
+// validates all overrides of config file and applies overrides that match node labels in order,
+// returns names of fields that are changed.
+func (t *Type) applyOverrides(configByte []byte) ([]string, error) {
+	file := struct {
+		Overrides []override `yaml:"overrides"`
+	}{}
+
+	if err := yaml.Unmarshal(configByte, &file); err != nil {
+		return nil, errors.Wrap(err, "error in yaml.Unmarshal")
+	}
+
+	currentLabels := nodeLabels.Load()
+	result := make([]string, 0)
+
+	for i, item := range file.Overrides {
+		name := item.Name
+		if len(name) == 0 {
+			name = "#" + strconv.Itoa(i)
+		}
+
+		selector, err := labels.Parse(item.NodeSelector)
+		if err != nil {
+			return nil, errors.Wrapf(errInvalidOverride, "%s: %s", name, err.Error())
+		}
+
+		keys := make(map[string]any)
+
+		if err := item.Config.Decode(&keys); err != nil {
+			return nil, errors.Wrapf(errInvalidOverride, "%s: %s", name, err.Error())
+		}
+
+		fields := fieldNames(keys)
+
+		if len(fields) != len(keys) {
+			return nil, errors.Wrapf(errUnknownField, "override %s", name)
+		}
+
+		for _, field := range fields {
+			if slices.Contains(immutableFields, field) {
+				return nil, errors.Wrapf(errImmutableField, "override %s: %s", name, field)
+			}
+		}
+
+		// overrides are applied only when node labels are known
+		if currentLabels == nil || !selector.Matches(*currentLabels) {
+			continue
+		}
+
+		if err := item.Config.Decode(t); err != nil {
+			return nil, errors.Wrapf(errInvalidOverride, "%s: %s", name, err.Error())
+		}
+
+		log.Infof("Using config override %s", name)
+
+		result = append(result, fields...)
+	}
+
+	return result, nil
+}
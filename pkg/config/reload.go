@@ -0,0 +1,181 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	errImmutableField = errors.New("fields can not be changed without restart")
+	errNotLoaded      = errors.New("config is not loaded")
+)
+
+// fields that are used only on start, changes of them are refused.
+var immutableFields = []string{
+	"ConfigFile",
+	"ConfigReloadPeriod",
+	"KubeConfigFile",
+	"NodeName",
+	"ResourceName",
+	"Standalone",
+	"Endpoint",
+	"APIVersion",
+	"InstanceEndpoint",
+	"Period",
+	"ScheduledPeriod",
+	"MaxBackoff",
+	"FailureThreshold",
+	"RequestTimeout",
+	"ExitAfterNodeDrain",
+	"WebHTTPAddress",
+	"WebAdminAddress",
+	"WebTLSCert",
+	"WebTLSKey",
+	"WebPprof",
+	"WebDrain",
+	"TracingEndpoint",
+	"TracingSampleRatio",
+	"WebhookInsecure",
+	"WebhookProxy",
+	"LogLevel",
+	"LogPretty",
+	"SentryDSN",
+}
+
+// Reload reads config file again and replaces current config if new config is valid.
+func Reload() error {
+	if flagsConfig == nil || loadedConfig == nil {
+		return errNotLoaded
+	}
+
+	newConfig, newSources, err := flagsConfig.load()
+	if err != nil {
+		return err
+	}
+
+	if err := newConfig.Check(); err != nil {
+		return errors.Wrap(err, "new config is not valid")
+	}
+
+	if changed := changedFields(loadedConfig, newConfig, immutableFields); len(changed) > 0 {
+		return errors.Wrap(errImmutableField, strings.Join(changed, ","))
+	}
+
+	// values of fields that are used only on start can be changed after start, for example node name in standalone mode
+	copyFields(Get(), newConfig, immutableFields)
+
+	sources.Store(&newSources)
+	current.Store(newConfig)
+
+	return nil
+}
+
+// WatchConfigFile reloads config file when it's changed, afterReload is called with result of every reload.
+func WatchConfigFile(ctx context.Context, period time.Duration, afterReload func(err error)) {
+	configFile := *Get().ConfigFile
+
+	if len(configFile) == 0 || period <= 0 {
+		return
+	}
+
+	log.Infof("Watching config file %s every %s", configFile, period)
+
+	lastContent, err := os.ReadFile(configFile)
+	if err != nil {
+		log.WithError(err).Error("error reading config file")
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// ConfigMap volumes are updated with symlink swap, content is compared instead of modification time
+		content, err := os.ReadFile(configFile)
+		if err != nil {
+			log.WithError(err).Error("error reading config file")
+
+			continue
+		}
+
+		if bytes.Equal(content, lastContent) {
+			continue
+		}
+
+		lastContent = content
+
+		afterReload(Reload())
+	}
+}
+
+// returns copy of config, values of pointers are copied too.
+func (t *Type) clone() *Type {
+	source := reflect.ValueOf(t).Elem()
+	result := reflect.New(source.Type()).Elem()
+
+	for i := range source.NumField() {
+		field := source.Field(i)
+
+		if field.Kind() != reflect.Ptr || field.IsNil() {
+			result.Field(i).Set(field)
+
+			continue
+		}
+
+		value := reflect.New(field.Type().Elem())
+		value.Elem().Set(field.Elem())
+
+		result.Field(i).Set(value)
+	}
+
+	return result.Addr().Interface().(*Type) //nolint:forcetypeassert
+}
+
+func copyFields(source, destination *Type, fields []string) {
+	sourceValue := reflect.ValueOf(source).Elem()
+	destinationValue := reflect.ValueOf(destination).Elem()
+
+	for _, field := range fields {
+		destinationValue.FieldByName(field).Set(sourceValue.FieldByName(field))
+	}
+}
+
+// returns names of fields that have different values.
+func changedFields(oldConfig, newConfig *Type, fields []string) []string {
+	result := make([]string, 0)
+
+	oldValue := reflect.ValueOf(oldConfig).Elem()
+	newValue := reflect.ValueOf(newConfig).Elem()
+
+	for _, field := range fields {
+		if !reflect.DeepEqual(oldValue.FieldByName(field).Interface(), newValue.FieldByName(field).Interface()) {
+			result = append(result, field)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,387 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/utils"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// prefix of environment variables that override config fields.
+const EnvPrefix = "ANTH_"
+
+// Source is a place where value of config field is defined.
+type Source string
+
+const (
+	SourceDefault  Source = "default"
+	SourceFile     Source = "file"
+	SourceOverride Source = "override"
+	SourceEnv      Source = "env"
+	SourceEnvFile  Source = "env-file"
+	SourceFlag     Source = "flag"
+)
+
+var (
+	errUnsupportedType = errors.New("unsupported type of config field")
+	errEnvFileConflict = errors.New("only one of environment variables can be defined")
+)
+
+// sources of current config fields.
+var sources atomic.Pointer[map[string]Source]
+
+// environment variables that were used before ANTH_ prefix, values of them are defaults of flags.
+var legacyEnvNames = map[string]string{
+	"TelegramToken": "TELEGRAM_TOKEN",
+	"WebHookURL":    "WEBHOOK_URL",
+	"DrainToken":    "DRAIN_TOKEN",
+}
+
+// names of flags by config fields, flags are used to name environment variables.
+var fieldFlags = getFieldFlags()
+
+// Option is a value of config field with source of value.
+type Option struct {
+	Field  string
+	Flag   string
+	Env    string
+	Source Source
+	Value  string
+}
+
+// EnvName returns name of environment variable for flag, for example taint.effect is ANTH_TAINT_EFFECT.
+func EnvName(flagName string) string {
+	var result strings.Builder
+
+	result.WriteString(EnvPrefix)
+
+	var previous rune
+
+	for _, r := range flagName {
+		switch {
+		case r == '.' || r == '-':
+			result.WriteRune('_')
+		case unicode.IsUpper(r) && (unicode.IsLower(previous) || unicode.IsDigit(previous)):
+			result.WriteRune('_')
+			result.WriteRune(r)
+		default:
+			result.WriteRune(unicode.ToUpper(r))
+		}
+
+		previous = r
+	}
+
+	return result.String()
+}
+
+// Describe returns values of current config with sources, secrets are redacted.
+func Describe() []Option {
+	currentSources := make(map[string]Source)
+	if s := sources.Load(); s != nil {
+		currentSources = *s
+	}
+
+	value := reflect.ValueOf(Get()).Elem()
+	result := make([]Option, 0, value.NumField())
+
+	for i := range value.NumField() {
+		name := value.Type().Field(i).Name
+
+		option := Option{
+			Field:  name,
+			Source: SourceDefault,
+		}
+
+		if flagName, ok := fieldFlags[name]; ok {
+			option.Flag = flagName
+			option.Env = EnvName(flagName)
+		}
+
+		if source, ok := currentSources[name]; ok {
+			option.Source = source
+		}
+
+		if field := value.Field(i); !field.IsNil() {
+			option.Value = redactValue(name, fmt.Sprint(field.Elem().Interface()))
+		}
+
+		result = append(result, option)
+	}
+
+	return result
+}
+
+// applies config file, environment variables and flags on copy of config,
+// precedence is flags > env > node overrides > file > defaults.
+func (t *Type) load() (*Type, map[string]Source, error) {
+	result := t.clone()
+
+	// config file is read from path in environment variable, unless it's set with flag
+	if envValue, ok := os.LookupEnv(EnvName(fieldFlags["ConfigFile"])); ok && !isFlagSet(fieldFlags["ConfigFile"]) {
+		result.ConfigFile = &envValue
+	}
+
+	value := reflect.ValueOf(result).Elem()
+
+	// secrets in legacy environment variables can be read from file too, file is used as default like value
+	legacySources := make(map[string]Source)
+
+	for name, envName := range legacyEnvNames {
+		envValue, source, err := lookupEnv(name, envName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if source != SourceEnvFile {
+			continue
+		}
+
+		if err := setField(value.FieldByName(name), envValue); err != nil {
+			return nil, nil, errors.Wrapf(err, "error parsing %s_FILE", envName)
+		}
+
+		legacySources[name] = source
+	}
+
+	resultSources, err := result.loadFile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name, source := range legacySources {
+		if _, ok := resultSources[name]; !ok {
+			resultSources[name] = source
+		}
+	}
+
+	for name, flagName := range fieldFlags {
+		envValue, source, err := lookupEnv(name, EnvName(flagName))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(source) == 0 {
+			continue
+		}
+
+		if err := setField(value.FieldByName(name), envValue); err != nil {
+			return nil, nil, errors.Wrapf(err, "error parsing %s", EnvName(flagName))
+		}
+
+		resultSources[name] = source
+	}
+
+	// flags that are set in command line, values of them are in original config
+	flagsValue := reflect.ValueOf(t).Elem()
+
+	for name, flagName := range fieldFlags {
+		if isFlagSet(flagName) {
+			value.FieldByName(name).Set(flagsValue.FieldByName(name))
+
+			resultSources[name] = SourceFlag
+		}
+	}
+
+	return result.clone(), resultSources, nil
+}
+
+// applies config file and matching overrides, returns sources of fields that are defined in file.
+func (t *Type) loadFile() (map[string]Source, error) {
+	result := make(map[string]Source)
+
+	if t.ConfigFile == nil || len(*t.ConfigFile) == 0 {
+		return result, nil
+	}
+
+	configByte, err := os.ReadFile(*t.ConfigFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in os.ReadFile")
+	}
+
+	err = yaml.Unmarshal(configByte, t)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in yaml.Unmarshal")
+	}
+
+	keys := make(map[string]any)
+
+	if err := yaml.Unmarshal(configByte, &keys); err != nil {
+		return nil, errors.Wrap(err, "error in yaml.Unmarshal")
+	}
+
+	for _, name := range fieldNames(keys) {
+		result[name] = SourceFile
+	}
+
+	overrideFields, err := t.applyOverrides(configByte)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range overrideFields {
+		result[name] = SourceOverride
+	}
+
+	return result, nil
+}
+
+// returns names of config fields by yaml keys, yaml keys are field names in lower case.
+func fieldNames(keys map[string]any) []string {
+	result := make([]string, 0, len(keys))
+
+	configType := reflect.TypeOf(Type{})
+
+	for i := range configType.NumField() {
+		if _, ok := keys[strings.ToLower(configType.Field(i).Name)]; ok {
+			result = append(result, configType.Field(i).Name)
+		}
+	}
+
+	return result
+}
+
+// returns value of environment variable, secrets can be read from file that is defined in <name>_FILE variable.
+func lookupEnv(name, envName string) (string, Source, error) {
+	envValue, ok := os.LookupEnv(envName)
+
+	fileName, fileOk := os.LookupEnv(envName + "_FILE")
+	if !fileOk || len(secretTag(name)) == 0 {
+		if ok {
+			return envValue, SourceEnv, nil
+		}
+
+		return "", "", nil
+	}
+
+	if ok {
+		return "", "", errors.Wrapf(errEnvFileConflict, "%s, %s_FILE", envName, envName)
+	}
+
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "error reading %s_FILE", envName)
+	}
+
+	return strings.TrimSpace(string(content)), SourceEnvFile, nil
+}
+
+// returns copy of config that can be printed, secrets are redacted.
+func (t *Type) redacted() *Type {
+	result := t.clone()
+	value := reflect.ValueOf(result).Elem()
+
+	for i := range value.NumField() {
+		if stringValue, ok := value.Field(i).Interface().(*string); ok && stringValue != nil {
+			*stringValue = redactValue(value.Type().Field(i).Name, *stringValue)
+		}
+	}
+
+	return result
+}
+
+// secret fields are tagged with secret:"true", or secret:"url" when only host of URL can be printed.
+func secretTag(name string) string {
+	field, _ := reflect.TypeOf(Type{}).FieldByName(name)
+
+	return field.Tag.Get("secret")
+}
+
+func redactValue(name, value string) string {
+	if len(value) == 0 {
+		return value
+	}
+
+	switch secretTag(name) {
+	case "":
+		return value
+	case "url":
+		return utils.RedactURL(value)
+	default:
+		return utils.Redacted
+	}
+}
+
+func setField(field reflect.Value, value string) error {
+	var (
+		parsed any
+		err    error
+	)
+
+	switch field.Interface().(type) {
+	case *string:
+		parsed = value
+	case *bool:
+		parsed, err = strconv.ParseBool(value)
+	case *int:
+		parsed, err = strconv.Atoi(value)
+	case *float64:
+		parsed, err = strconv.ParseFloat(value, 64)
+	case *time.Duration:
+		parsed, err = time.ParseDuration(value)
+	default:
+		return errors.Wrap(errUnsupportedType, field.Type().String())
+	}
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	pointer := reflect.New(field.Type().Elem())
+	pointer.Elem().Set(reflect.ValueOf(parsed))
+
+	field.Set(pointer)
+
+	return nil
+}
+
+func isFlagSet(name string) bool {
+	result := false
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			result = true
+		}
+	})
+
+	return result
+}
+
+// flags are found by pointers of config fields.
+func getFieldFlags() map[string]string {
+	result := make(map[string]string)
+
+	value := reflect.ValueOf(&config).Elem()
+
+	flag.VisitAll(func(f *flag.Flag) {
+		flagPointer := reflect.ValueOf(f.Value).Pointer()
+
+		for i := range value.NumField() {
+			if field := value.Field(i); !field.IsNil() && field.Pointer() == flagPointer {
+				result[value.Type().Field(i).Name] = f.Name
+			}
+		}
+	})
+
+	return result
+}
@@ -0,0 +1,95 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package events
+
+import (
+	"context"
+	"net/url"
+	"slices"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/imds"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// detect newest supported api version on startup.
+	APIVersionAuto = "auto"
+	// api version that is used if detection fails.
+	DefaultAPIVersion = "2020-07-01"
+)
+
+// api versions of scheduled events that are supported by handler, newest is last.
+// 2017-08-01 removed underscore prefix from resource names, 2017-11-01 added Preempt,
+// 2019-01-01 added Terminate, 2019-04-01 added Description, 2019-08-01 added EventSource,
+// 2020-07-01 added DurationInSeconds.
+var supportedAPIVersions = []string{
+	"2017-08-01",
+	"2017-11-01",
+	"2019-01-01",
+	"2019-04-01",
+	"2019-08-01",
+	"2020-07-01",
+}
+
+// ResolveEndpoint returns scheduled events endpoint with api version,
+// api version in endpoint url has priority over apiVersion.
+func ResolveEndpoint(ctx context.Context, endpoint string, apiVersion string) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "error in url.Parse")
+	}
+
+	query := endpointURL.Query()
+
+	if len(query.Get("api-version")) > 0 {
+		return endpoint, nil
+	}
+
+	if len(apiVersion) == 0 || apiVersion == APIVersionAuto {
+		apiVersion = detectAPIVersion(ctx, endpointURL)
+	}
+
+	query.Set("api-version", apiVersion)
+	endpointURL.RawQuery = query.Encode()
+
+	return endpointURL.String(), nil
+}
+
+// returns newest api version that is supported by IMDS and handler.
+func detectAPIVersion(ctx context.Context, endpointURL *url.URL) string {
+	versionsURL := url.URL{
+		Scheme: endpointURL.Scheme,
+		Host:   endpointURL.Host,
+		Path:   "/metadata/versions",
+	}
+
+	versions, err := imds.GetAPIVersions(ctx, versionsURL.String())
+	if err != nil {
+		log.WithError(err).Warnf("error detecting api version, using %s", DefaultAPIVersion)
+
+		return DefaultAPIVersion
+	}
+
+	for i := len(supportedAPIVersions) - 1; i >= 0; i-- {
+		if slices.Contains(versions, supportedAPIVersions[i]) {
+			log.Infof("Detected api version %s", supportedAPIVersions[i])
+
+			return supportedAPIVersions[i]
+		}
+	}
+
+	log.Warnf("IMDS does not support known api versions %v, using %s", versions, DefaultAPIVersion)
+
+	return DefaultAPIVersion
+}
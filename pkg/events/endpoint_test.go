@@ -0,0 +1,68 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package events_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveEndpoint(t *testing.T) {
+	t.Parallel()
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/metadata/versions", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"apiVersions":["2017-03-01","2019-08-01","2019-08-15","2021-02-01"]}`))
+	})
+
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	ctx := context.TODO()
+
+	// newest version that is supported by IMDS and handler
+	endpoint, err := events.ResolveEndpoint(ctx, testServer.URL+"/metadata/scheduledevents", events.APIVersionAuto)
+	require.NoError(t, err)
+	require.Equal(t, testServer.URL+"/metadata/scheduledevents?api-version=2019-08-01", endpoint)
+
+	endpoint, err = events.ResolveEndpoint(ctx, testServer.URL+"/metadata/scheduledevents", "2020-07-01")
+	require.NoError(t, err)
+	require.Equal(t, testServer.URL+"/metadata/scheduledevents?api-version=2020-07-01", endpoint)
+
+	olderServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"apiVersions":["2017-08-01","2019-04-01"]}`))
+	}))
+	defer olderServer.Close()
+
+	endpoint, err = events.ResolveEndpoint(ctx, olderServer.URL+"/metadata/scheduledevents", events.APIVersionAuto)
+	require.NoError(t, err)
+	require.Equal(t, olderServer.URL+"/metadata/scheduledevents?api-version=2019-04-01", endpoint)
+
+	// api version in endpoint has priority
+	endpoint, err = events.ResolveEndpoint(ctx, testServer.URL+"/metadata/scheduledevents?api-version=2019-01-01", events.APIVersionAuto)
+	require.NoError(t, err)
+	require.Equal(t, testServer.URL+"/metadata/scheduledevents?api-version=2019-01-01", endpoint)
+
+	// detection fails
+	failedServer := httptest.NewServer(http.NotFoundHandler())
+	defer failedServer.Close()
+
+	endpoint, err = events.ResolveEndpoint(ctx, failedServer.URL+"/metadata/scheduledevents", events.APIVersionAuto)
+	require.NoError(t, err)
+	require.Equal(t, failedServer.URL+"/metadata/scheduledevents?api-version="+events.DefaultAPIVersion, endpoint)
+}
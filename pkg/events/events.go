@@ -15,24 +15,60 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/cache"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/metrics"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/tracing"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
-	"github.com/maksim-paskal/aks-node-termination-handler/pkg/utils"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	requestTimeout = 10 * time.Second
-	readInterval   = 5 * time.Second
-	eventCacheTTL  = 10 * time.Minute
+	requestTimeout   = 10 * time.Second
+	readInterval     = 5 * time.Second
+	eventCacheTTL    = 10 * time.Minute
+	maxBackoff       = 60 * time.Second
+	failureThreshold = 5
 )
 
+// ResponseError is returned when endpoint responds with not OK status.
+type ResponseError struct {
+	StatusCode int
+	// time to wait before next request, from Retry-After header
+	RetryAfter time.Duration
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("endpoint returned StatusCode=%d, RetryAfter=%s", e.StatusCode, e.RetryAfter)
+}
+
+// ProcessingError is returned when events were read but callback failed to process them,
+// processing errors do not count as failed reads.
+type ProcessingError struct {
+	Err error
+}
+
+func (e *ProcessingError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProcessingError) Unwrap() error {
+	return e.Err
+}
+
+// ErrSimulatedEventPending is returned when previous simulated event is not processed yet.
+var ErrSimulatedEventPending = errors.New("previous simulated event is not processed yet")
+
 var httpClient = &http.Client{
 	Transport: metrics.NewInstrumenter("events").InstrumentedRoundTripper(),
 }
@@ -46,6 +82,12 @@ type Reader struct {
 	RequestTimeout time.Duration
 	// intervals of reading events
 	Period time.Duration
+	// intervals of reading events while resource has scheduled events
+	ScheduledPeriod time.Duration
+	// maximum interval between attempts after failed reads
+	MaxBackoff time.Duration
+	// number of consecutive failed reads after which ReadFailed is called
+	FailureThreshold int
 	// name of the node
 	NodeName string
 	// name of the resource to watch
@@ -55,20 +97,67 @@ type Reader struct {
 	// EventReceived is a function that will be called when event received
 	// return true if you want to stop reading events
 	EventReceived func(ctx context.Context, event types.ScheduledEventsEvent) (bool, error) `json:"-"`
+	// EventUpdated is a function that will be called when received event changed status
+	EventUpdated func(ctx context.Context, event types.ScheduledEventsEvent) error `json:"-"`
+	// EventsCleared is a function that will be called when there are no more events for resource
+	EventsCleared func(ctx context.Context) error `json:"-"`
+	// ReadFailed is a function that will be called once when consecutive failed reads reach FailureThreshold
+	ReadFailed func(ctx context.Context, err error) `json:"-"`
+	// resource had events before reading was started, first read without events will call EventsCleared
+	HadEvents bool
+	// resource had events on last read
+	hasEvents bool
+	// last known status of resource events
+	eventsStatus map[string]types.EventStatus
+	// resource has events that are not started yet
+	hasScheduledEvents bool
+	// number of consecutive failed reads
+	failures atomic.Int32
+	// unix time of last iteration of reading loop
+	lastHeartbeat atomic.Int64
+	// unix time of last successful read
+	lastSuccess atomic.Int64
+	// reader is reading endpoint or processing events
+	busy atomic.Bool
+	// result of last read of endpoint
+	lastRead      PollResult
+	lastReadMutex sync.RWMutex
+	// simulated events are processed by reading loop, so callbacks are never called concurrently
+	simulatedEvents chan simulatedEvent
+}
+
+type simulatedEvent struct {
+	event      types.ScheduledEventsEvent
+	clearEvent bool
+}
+
+// PollResult is a result of reading endpoint.
+type PollResult struct {
+	Time     time.Time                  `json:"time"`
+	Error    string                     `json:"error,omitempty"`
+	Document *types.ScheduledEventsType `json:"document,omitempty"`
 }
 
 func NewReader() *Reader {
 	return &Reader{
-		Method:         http.MethodGet,
-		Endpoint:       "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01",
-		RequestTimeout: requestTimeout,
-		Period:         readInterval,
+		Method:           http.MethodGet,
+		Endpoint:         "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01",
+		RequestTimeout:   requestTimeout,
+		Period:           readInterval,
+		ScheduledPeriod:  readInterval,
+		MaxBackoff:       maxBackoff,
+		FailureThreshold: failureThreshold,
+		simulatedEvents:  make(chan simulatedEvent, 1),
 	}
 }
 
 func (r *Reader) ReadEvents(ctx context.Context) {
 	log.Infof("Start reading events %s", r.String())
 
+	metrics.ReadEventsPeriodSeconds.WithLabelValues(r.getMetricsLabels()...).Set(r.Period.Seconds())
+
+	r.hasEvents = r.HadEvents
+
 	if r.BeforeReading != nil {
 		if err := r.BeforeReading(ctx); err != nil {
 			log.WithError(err).Error("Error in BeforeReading")
@@ -76,11 +165,34 @@ func (r *Reader) ReadEvents(ctx context.Context) {
 	}
 
 	for ctx.Err() == nil {
+		r.lastHeartbeat.Store(time.Now().UnixNano())
+		r.busy.Store(true)
+
 		stopReadingEvents, err := r.ReadEndpoint(ctx)
-		if err != nil {
-			metrics.ErrorReadingEndpoint.WithLabelValues(r.getMetricsLabels()...).Inc()
 
-			log.WithError(err).Error()
+		r.busy.Store(false)
+		r.lastHeartbeat.Store(time.Now().UnixNano())
+
+		// read was canceled by stopping of reading loop, it's not a failed read
+		if ctx.Err() != nil {
+			return
+		}
+
+		// endpoint was read, backoff and readiness depend only on reading endpoint
+		processingError := &ProcessingError{}
+		if errors.As(err, &processingError) {
+			r.processingFailed(err)
+
+			err = nil
+		}
+
+		if err != nil {
+			r.readFailed(ctx, err)
+		} else {
+			r.failures.Store(0)
+			r.lastSuccess.Store(time.Now().UnixNano())
+			metrics.ReadEventsConsecutiveFailures.WithLabelValues(r.getMetricsLabels()...).Set(0)
+			metrics.ReadEventsLastSuccessTimestamp.WithLabelValues(r.getMetricsLabels()...).SetToCurrentTime()
 		}
 
 		if stopReadingEvents {
@@ -89,10 +201,126 @@ func (r *Reader) ReadEvents(ctx context.Context) {
 			return
 		}
 
-		utils.SleepWithContext(ctx, r.Period)
+		r.waitNextRead(ctx, r.nextInterval(err))
+	}
+}
+
+// waits for next read, simulated events are processed while waiting.
+func (r *Reader) waitNextRead(ctx context.Context, interval time.Duration) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case simulated := <-r.simulatedEvents:
+			r.busy.Store(true)
+
+			if err := r.processSimulatedEvent(ctx, simulated); err != nil {
+				r.processingFailed(err)
+			}
+
+			r.busy.Store(false)
+			r.lastHeartbeat.Store(time.Now().UnixNano())
+		}
 	}
 }
 
+func (r *Reader) processingFailed(err error) {
+	metrics.ErrorProcessingEvents.WithLabelValues(r.getMetricsLabels()...).Inc()
+	log.WithError(err).Error("error processing events")
+}
+
+// LastSuccess returns time of last successful read, zero if there was no successful reads.
+func (r *Reader) LastSuccess() time.Time {
+	return unixTime(r.lastSuccess.Load())
+}
+
+// IsProgressing returns true if reading loop is not stuck,
+// processing of events (for example node drain) can take long time and is counted as progress.
+func (r *Reader) IsProgressing() bool {
+	if r.busy.Load() {
+		return true
+	}
+
+	lastHeartbeat := unixTime(r.lastHeartbeat.Load())
+
+	// reading loop is not started yet
+	if lastHeartbeat.IsZero() {
+		return true
+	}
+
+	return time.Since(lastHeartbeat) < r.MaxInterval()
+}
+
+// MaxInterval returns maximum expected interval between reads.
+func (r *Reader) MaxInterval() time.Duration {
+	return 2*max(r.Period, r.ScheduledPeriod, r.MaxBackoff) + r.RequestTimeout //nolint:mnd
+}
+
+func unixTime(value int64) time.Time {
+	if value == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, value)
+}
+
+// ConsecutiveFailures returns number of consecutive failed reads.
+func (r *Reader) ConsecutiveFailures() int {
+	return int(r.failures.Load())
+}
+
+func (r *Reader) readFailed(ctx context.Context, err error) {
+	failures := r.failures.Add(1)
+
+	metrics.ErrorReadingEndpoint.WithLabelValues(r.getMetricsLabels()...).Inc()
+	metrics.ReadEventsConsecutiveFailures.WithLabelValues(r.getMetricsLabels()...).Set(float64(failures))
+
+	responseError := &ResponseError{}
+	if errors.As(err, &responseError) && responseError.StatusCode == http.StatusTooManyRequests {
+		metrics.ReadEventsThrottledTotal.WithLabelValues(r.getMetricsLabels()...).Inc()
+	}
+
+	log.WithError(err).Errorf("error reading events, consecutive failures %d", failures)
+
+	if int(failures) == r.FailureThreshold && r.ReadFailed != nil {
+		r.ReadFailed(ctx, err)
+	}
+}
+
+// returns interval before next read, failed reads are retried with exponential backoff and jitter.
+func (r *Reader) nextInterval(err error) time.Duration {
+	if err == nil {
+		if r.hasScheduledEvents && r.ScheduledPeriod > 0 {
+			return r.ScheduledPeriod
+		}
+
+		return r.Period
+	}
+
+	backoff := r.Period
+
+	for i := int32(1); i < r.failures.Load() && backoff < r.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	backoff = min(backoff, r.MaxBackoff)
+
+	// full jitter in upper half of backoff, nodes will not retry at the same time
+	backoff = backoff/2 + rand.N(backoff/2+1) //nolint:gosec,mnd
+
+	responseError := &ResponseError{}
+	if errors.As(err, &responseError) && responseError.RetryAfter > backoff {
+		return responseError.RetryAfter
+	}
+
+	return backoff
+}
+
 func (r *Reader) getScheduledEvents(ctx context.Context) (*types.ScheduledEventsType, error) {
 	ctx, cancel := context.WithTimeout(ctx, r.RequestTimeout)
 	defer cancel()
@@ -119,6 +347,13 @@ func (r *Reader) getScheduledEvents(ctx context.Context) (*types.ScheduledEvents
 
 	log.Debugf("response status: %s", resp.Status)
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ResponseError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, errors.Wrap(err, "error in io.ReadAll")
@@ -141,38 +376,226 @@ func (r *Reader) getScheduledEvents(ctx context.Context) (*types.ScheduledEvents
 	return &message, nil
 }
 
+// SimulateEvent queues synthetic event, reading loop passes it to the same callbacks as events from endpoint,
+// with clearEvent EventsCleared is called after processing as if event is gone from document.
+func (r *Reader) SimulateEvent(event types.ScheduledEventsEvent, clearEvent bool) error {
+	select {
+	case r.simulatedEvents <- simulatedEvent{event: event, clearEvent: clearEvent}:
+		return nil
+	default:
+		return ErrSimulatedEventPending
+	}
+}
+
+func (r *Reader) processSimulatedEvent(ctx context.Context, simulated simulatedEvent) error {
+	event := simulated.event
+
+	log.Warnf("Simulating event %+v", event)
+
+	cache.Add(event.EventId, eventCacheTTL)
+
+	metrics.ScheduledEventsTotal.WithLabelValues(append(r.getMetricsLabels(), string(event.EventType))...).Inc()
+	metrics.EventLeadTimeSeconds.WithLabelValues(string(event.EventType)).Observe(event.TimeUntilStart().Seconds())
+
+	if r.EventReceived != nil {
+		ctx, span := tracing.Start(ctx, "ScheduledEvent", tracing.EventAttributes(event))
+
+		// simulated event never stops reading events
+		_, err := r.EventReceived(ctx, event)
+
+		tracing.End(span, err)
+
+		if err != nil {
+			return &ProcessingError{Err: errors.Wrap(err, "error in EventReceived")}
+		}
+	}
+
+	if simulated.clearEvent && r.EventsCleared != nil {
+		if err := r.EventsCleared(ctx); err != nil {
+			return &ProcessingError{Err: errors.Wrap(err, "error in EventsCleared")}
+		}
+	}
+
+	return nil
+}
+
+// LastRead returns result of last read of endpoint, document is from last successful read.
+func (r *Reader) LastRead() PollResult {
+	r.lastReadMutex.RLock()
+	defer r.lastReadMutex.RUnlock()
+
+	return r.lastRead
+}
+
+func (r *Reader) setLastRead(message *types.ScheduledEventsType, err error) {
+	r.lastReadMutex.Lock()
+	defer r.lastReadMutex.Unlock()
+
+	r.lastRead.Time = time.Now()
+	r.lastRead.Error = ""
+
+	if err != nil {
+		r.lastRead.Error = err.Error()
+
+		return
+	}
+
+	r.lastRead.Document = message
+}
+
 func (r *Reader) ReadEndpoint(ctx context.Context) (bool, error) {
+	fetchStart := time.Now()
+
 	message, err := r.getScheduledEvents(ctx)
+
+	fetchEnd := time.Now()
+
+	r.setLastRead(message, err)
+
 	if err != nil {
 		return false, errors.Wrap(err, "error in getScheduledEvents")
 	}
 
-	for _, event := range message.Events {
-		for _, resource := range event.Resources {
-			if resource == r.AzureResource {
-				log.Infof("%+v", message)
+	resourceEvents := r.getResourceEvents(message)
 
-				if cache.HasKey(event.EventId) {
-					log.Infof("Event %s already processed", event.EventId)
+	if len(resourceEvents) == 0 {
+		if r.hasEvents && r.EventsCleared != nil {
+			if err := r.EventsCleared(ctx); err != nil {
+				return false, &ProcessingError{Err: errors.Wrap(err, "error in EventsCleared")}
+			}
+		}
 
-					continue
-				}
+		r.hasEvents = false
+		r.hasScheduledEvents = false
+		r.eventsStatus = nil
 
-				// add to cache, ignore similar events for 10 minutes
-				cache.Add(event.EventId, eventCacheTTL)
+		return false, nil
+	}
+
+	r.hasEvents = true
+	r.hasScheduledEvents = false
+
+	for _, event := range resourceEvents {
+		if event.EventStatus == types.EventStatusScheduled {
+			r.hasScheduledEvents = true
+		}
+	}
 
-				metrics.ScheduledEventsTotal.WithLabelValues(append(r.getMetricsLabels(), string(event.EventType))...).Inc()
+	log.Infof("%+v", message)
 
-				if r.EventReceived != nil {
-					return r.EventReceived(ctx, event)
+	r.updateEventsStatus(resourceEvents)
+
+	for _, event := range resourceEvents {
+		previousStatus, seen := r.eventsStatus[event.EventId]
+		r.eventsStatus[event.EventId] = event.EventStatus
+
+		if cache.HasKey(event.EventId) {
+			log.Debugf("Event %s already processed", event.EventId)
+
+			// event is still in document, ignore it while it exists
+			cache.Add(event.EventId, eventCacheTTL)
+
+			if seen && previousStatus != event.EventStatus && r.EventUpdated != nil {
+				if err := r.eventUpdated(ctx, event); err != nil {
+					return false, &ProcessingError{Err: errors.Wrap(err, "error in EventUpdated")}
 				}
 			}
+
+			continue
+		}
+
+		// add to cache, ignore similar events for 10 minutes
+		cache.Add(event.EventId, eventCacheTTL)
+
+		metrics.ScheduledEventsTotal.WithLabelValues(append(r.getMetricsLabels(), string(event.EventType))...).Inc()
+		metrics.EventLeadTimeSeconds.WithLabelValues(string(event.EventType)).Observe(event.TimeUntilStart().Seconds())
+
+		if r.EventReceived != nil {
+			stopReadingEvents, err := r.eventReceived(ctx, event, fetchStart, fetchEnd)
+			if err != nil {
+				return stopReadingEvents, &ProcessingError{Err: errors.Wrap(err, "error in EventReceived")}
+			}
+
+			return stopReadingEvents, nil
 		}
 	}
 
 	return false, nil
 }
 
+// one trace covers handling of event, trace starts with request that returned event.
+func (r *Reader) eventReceived(ctx context.Context, event types.ScheduledEventsEvent, fetchStart, fetchEnd time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ScheduledEvent", trace.WithTimestamp(fetchStart), tracing.EventAttributes(event))
+
+	_, fetchSpan := tracing.Start(ctx, "imds.GetScheduledEvents", trace.WithTimestamp(fetchStart))
+	fetchSpan.End(trace.WithTimestamp(fetchEnd))
+
+	log.WithContext(ctx).Infof("Event %s received", event.EventId)
+
+	stopReadingEvents, err := r.EventReceived(ctx, event)
+
+	tracing.End(span, err)
+
+	return stopReadingEvents, err
+}
+
+func (r *Reader) eventUpdated(ctx context.Context, event types.ScheduledEventsEvent) error {
+	ctx, span := tracing.Start(ctx, "ScheduledEventUpdated", tracing.EventAttributes(event))
+
+	err := r.EventUpdated(ctx, event)
+
+	tracing.End(span, err)
+
+	return err
+}
+
+// removes status of events that are not in document anymore.
+func (r *Reader) updateEventsStatus(resourceEvents []types.ScheduledEventsEvent) {
+	eventsStatus := make(map[string]types.EventStatus, len(resourceEvents))
+
+	for _, event := range resourceEvents {
+		if status, ok := r.eventsStatus[event.EventId]; ok {
+			eventsStatus[event.EventId] = status
+		}
+	}
+
+	r.eventsStatus = eventsStatus
+}
+
+// returns events that affects watched resource.
+func (r *Reader) getResourceEvents(message *types.ScheduledEventsType) []types.ScheduledEventsEvent {
+	result := make([]types.ScheduledEventsEvent, 0)
+
+	for _, event := range message.Events {
+		for _, resource := range event.Resources {
+			if resource == r.AzureResource {
+				result = append(result, event)
+
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// returns duration from Retry-After header, header can be in seconds or http date.
+func parseRetryAfter(value string) time.Duration {
+	if len(value) == 0 {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if retryTime, err := http.ParseTime(value); err == nil {
+		return max(time.Until(retryTime), 0)
+	}
+
+	return 0
+}
+
 func (r *Reader) getMetricsLabels() []string {
 	return []string{
 		r.NodeName,
@@ -18,15 +18,21 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/metrics"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	log "github.com/sirupsen/logrus"
 )
 
+// default period of reader.
+const readInterval = 5 * time.Second
+
 func TestReadingEvents(t *testing.T) { //nolint:funlen
 	t.Parallel()
 
@@ -48,6 +54,13 @@ func TestReadingEvents(t *testing.T) { //nolint:funlen
 
 		_, _ = w.Write([]byte("a"))
 	})
+	handler.HandleFunc("/throttled", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Add("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	handler.HandleFunc("/error", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
 	handler.HandleFunc("/timeout", func(w http.ResponseWriter, r *http.Request) {
 		utils.SleepWithContext(r.Context(), 5*time.Second)
 		w.WriteHeader(http.StatusOK)
@@ -70,6 +83,33 @@ func TestReadingEvents(t *testing.T) { //nolint:funlen
 		_, _ = w.Write(message)
 	})
 
+	// requests are handled in server goroutines
+	var updatedRequests atomic.Int32
+
+	handler.HandleFunc("/updated", func(w http.ResponseWriter, _ *http.Request) {
+		requestNumber := int(updatedRequests.Add(1))
+
+		eventStatus := types.EventStatusScheduled
+		if requestNumber > 1 {
+			eventStatus = types.EventStatusStarted
+		}
+
+		message, _ := json.Marshal(types.ScheduledEventsType{
+			DocumentIncarnation: requestNumber,
+			Events: []types.ScheduledEventsEvent{
+				{
+					EventId:     "updated-event-id",
+					EventType:   types.EventTypePreempt,
+					EventStatus: eventStatus,
+					Resources:   []string{"resource1"},
+				},
+			},
+		})
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(message)
+	})
+
 	testServer := httptest.NewServer(handler)
 
 	t.Run("badjson", func(t *testing.T) {
@@ -134,6 +174,249 @@ func TestReadingEvents(t *testing.T) { //nolint:funlen
 		}
 	})
 
+	t.Run("throttled", func(t *testing.T) {
+		t.Parallel()
+
+		eventReader := events.NewReader()
+		eventReader.Endpoint = testServer.URL + "/throttled"
+
+		_, err := eventReader.ReadEndpoint(ctx)
+
+		responseError := &events.ResponseError{}
+		if !errors.As(err, &responseError) {
+			t.Fatalf("expected ResponseError, got %v", err)
+		}
+
+		if responseError.StatusCode != http.StatusTooManyRequests || responseError.RetryAfter != 7*time.Second {
+			t.Fatalf("unexpected response error %+v", responseError)
+		}
+
+		if lastRead := eventReader.LastRead(); len(lastRead.Error) == 0 || lastRead.Document != nil {
+			t.Fatalf("unexpected last read %+v", lastRead)
+		}
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		t.Parallel()
+
+		readFailed := 0
+
+		eventReader := events.NewReader()
+		eventReader.Endpoint = testServer.URL + "/error"
+		eventReader.Period = 10 * time.Millisecond
+		eventReader.MaxBackoff = 20 * time.Millisecond
+		eventReader.FailureThreshold = 3
+		eventReader.ReadFailed = func(_ context.Context, _ error) {
+			readFailed++
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		eventReader.ReadEvents(ctx)
+
+		if readFailed != 1 {
+			t.Fatalf("ReadFailed must be called once, got %d", readFailed)
+		}
+
+		if eventReader.ConsecutiveFailures() <= 3 {
+			t.Fatalf("unexpected consecutive failures %d", eventReader.ConsecutiveFailures())
+		}
+	})
+
+	t.Run("processing", func(t *testing.T) {
+		t.Parallel()
+
+		eventReader := events.NewReader()
+		eventReader.Endpoint = testServer.URL + "/document"
+		eventReader.NodeName = "processing"
+		eventReader.AzureResource = "resource2"
+		eventReader.Period = 10 * time.Millisecond
+		eventReader.FailureThreshold = 1
+		eventReader.EventReceived = func(_ context.Context, _ types.ScheduledEventsEvent) (bool, error) {
+			return false, errors.New("error in EventReceived") //nolint:goerr113
+		}
+		eventReader.ReadFailed = func(_ context.Context, err error) {
+			t.Errorf("ReadFailed must not be called, got %v", err)
+		}
+
+		_, err := eventReader.ReadEndpoint(ctx)
+
+		processingError := &events.ProcessingError{}
+		if !errors.As(err, &processingError) {
+			t.Fatalf("expected ProcessingError, got %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		eventReader.ReadEvents(ctx)
+
+		if eventReader.ConsecutiveFailures() != 0 || eventReader.LastSuccess().IsZero() {
+			t.Fatalf("processing errors must not be counted as failed reads, failures=%d", eventReader.ConsecutiveFailures())
+		}
+
+		if processingErrors := testutil.ToFloat64(metrics.ErrorProcessingEvents.WithLabelValues("processing", "resource2")); processingErrors < 2 {
+			t.Fatalf("unexpected processing errors %f", processingErrors)
+		}
+	})
+
+	t.Run("cleared", func(t *testing.T) {
+		t.Parallel()
+
+		eventsCleared := 0
+
+		eventReader := events.NewReader()
+		eventReader.Endpoint = testServer.URL + "/emptyjson"
+		eventReader.EventsCleared = func(_ context.Context) error {
+			eventsCleared++
+
+			return nil
+		}
+
+		for range 3 {
+			if _, err := eventReader.ReadEndpoint(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// resource had no events before reading was started
+		if eventsCleared != 0 {
+			t.Fatalf("EventsCleared must not be called, got %d", eventsCleared)
+		}
+
+		eventReader.Period = 10 * time.Millisecond
+		eventReader.HadEvents = true
+
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		eventReader.ReadEvents(ctx)
+
+		if eventsCleared != 1 {
+			t.Fatalf("EventsCleared must be called once, got %d", eventsCleared)
+		}
+
+		if lastRead := eventReader.LastRead(); lastRead.Time.IsZero() || lastRead.Document == nil || len(lastRead.Error) > 0 {
+			t.Fatalf("unexpected last read %+v", lastRead)
+		}
+	})
+
+	t.Run("updated", func(t *testing.T) {
+		t.Parallel()
+
+		receivedEvents := 0
+		updatedEvents := make([]types.ScheduledEventsEvent, 0)
+
+		eventReader := events.NewReader()
+		eventReader.Endpoint = testServer.URL + "/updated"
+		eventReader.AzureResource = "resource1"
+		eventReader.EventReceived = func(_ context.Context, _ types.ScheduledEventsEvent) (bool, error) {
+			receivedEvents++
+
+			return false, nil
+		}
+		eventReader.EventUpdated = func(_ context.Context, event types.ScheduledEventsEvent) error {
+			updatedEvents = append(updatedEvents, event)
+
+			return nil
+		}
+
+		for range 3 {
+			if _, err := eventReader.ReadEndpoint(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if receivedEvents != 1 {
+			t.Fatalf("EventReceived must be called once, got %d", receivedEvents)
+		}
+
+		if len(updatedEvents) != 1 || updatedEvents[0].EventStatus != types.EventStatusStarted {
+			t.Fatalf("EventUpdated must be called once with started event, got %+v", updatedEvents)
+		}
+	})
+
+	t.Run("simulate", func(t *testing.T) {
+		t.Parallel()
+
+		receivedEvents := make(chan types.ScheduledEventsEvent, 2)
+
+		var eventsCleared atomic.Int32
+
+		eventReader := events.NewReader()
+		eventReader.Endpoint = testServer.URL + "/emptyjson"
+		eventReader.NodeName = "simulate"
+		eventReader.AzureResource = "simulate-resource"
+		eventReader.Period = 10 * time.Millisecond
+		eventReader.EventReceived = func(_ context.Context, event types.ScheduledEventsEvent) (bool, error) {
+			receivedEvents <- event
+
+			// simulated event never stops reading events
+			return true, nil
+		}
+		eventReader.EventsCleared = func(_ context.Context) error {
+			eventsCleared.Add(1)
+
+			return nil
+		}
+
+		scheduledEventsTotal := metrics.ScheduledEventsTotal.WithLabelValues("simulate", "simulate-resource", "Reboot")
+		scheduledEventsBefore := testutil.ToFloat64(scheduledEventsTotal)
+
+		if err := eventReader.SimulateEvent(types.ScheduledEventsEvent{EventId: "simulated1", EventType: types.EventTypeReboot, EventSource: types.EventSourceSimulated}, false); err != nil { //nolint:lll
+			t.Fatal(err)
+		}
+
+		// event is queued until reading loop processes it
+		if err := eventReader.SimulateEvent(types.ScheduledEventsEvent{EventId: "simulated2"}, false); !errors.Is(err, events.ErrSimulatedEventPending) {
+			t.Fatalf("expected ErrSimulatedEventPending, got %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			eventReader.ReadEvents(ctx)
+		}()
+
+		waitEvent := func(eventID string) {
+			select {
+			case event := <-receivedEvents:
+				if event.EventId != eventID {
+					t.Errorf("unexpected event %+v", event)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("simulated event %s was not received", eventID)
+			}
+		}
+
+		waitEvent("simulated1")
+
+		if err := eventReader.SimulateEvent(types.ScheduledEventsEvent{EventId: "simulated3", EventType: types.EventTypeReboot, EventSource: types.EventSourceSimulated}, true); err != nil { //nolint:lll
+			t.Error(err)
+		}
+
+		waitEvent("simulated3")
+
+		// reading loop must not be stopped by simulated event
+		time.Sleep(50 * time.Millisecond)
+
+		cancel()
+		<-done
+
+		// only simulated event with clearEvent, resource had no events before reading was started
+		if cleared := eventsCleared.Load(); cleared != 1 {
+			t.Fatalf("EventsCleared must be called once, got %d", cleared)
+		}
+
+		if total := testutil.ToFloat64(scheduledEventsTotal) - scheduledEventsBefore; total != 2 {
+			t.Fatalf("unexpected scheduled events total %f", total)
+		}
+	})
+
 	t.Run("document", func(t *testing.T) {
 		t.Parallel()
 
@@ -161,5 +444,13 @@ func TestReadingEvents(t *testing.T) { //nolint:funlen
 		if receivedDocument.EventId == "" {
 			t.Error("unexpected event id")
 		}
+
+		if lastSuccess := testutil.ToFloat64(metrics.ReadEventsLastSuccessTimestamp.WithLabelValues("", "resource1")); lastSuccess <= 0 {
+			t.Errorf("unexpected last success timestamp %f", lastSuccess)
+		}
+
+		if period := testutil.ToFloat64(metrics.ReadEventsPeriodSeconds.WithLabelValues("", "resource1")); period != readInterval.Seconds() {
+			t.Errorf("unexpected period %f", period)
+		}
 	})
 }
@@ -0,0 +1,146 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// time to wait for command output after command was killed.
+const execWaitDelay = 5 * time.Second
+
+var errNoCommand = errors.New("command is empty")
+
+type ExecResult struct {
+	Command  string
+	ExitCode int
+	Duration time.Duration
+	Output   string
+	Error    error
+}
+
+func (r *ExecResult) String() string {
+	if r.Error != nil {
+		return fmt.Sprintf("Command %s failed in %s: %s", r.Command, r.Duration.Round(time.Millisecond), r.Error.Error())
+	}
+
+	return fmt.Sprintf("Command %s exited with %d in %s", r.Command, r.ExitCode, r.Duration.Round(time.Millisecond))
+}
+
+// RunExecHooks runs commands one by one, every command is a list of program and arguments,
+// it receives event in environment variables and as JSON in stdin.
+func RunExecHooks(ctx context.Context, commands [][]string, resourceName string, event types.ScheduledEventsEvent, timeout time.Duration) []*ExecResult {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.WithError(err).Error("error in json.Marshal")
+
+		return nil
+	}
+
+	results := make([]*ExecResult, 0, len(commands))
+
+	for _, command := range commands {
+		result := runExecHook(ctx, command, eventEnv(resourceName, event), body, timeout)
+
+		logger := log.WithFields(log.Fields{
+			"command": result.Command,
+			"output":  result.Output,
+		})
+
+		if result.Error != nil {
+			logger.WithError(result.Error).Error(result.String())
+		} else {
+			logger.Info(result.String())
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func runExecHook(ctx context.Context, args []string, env []string, body []byte, timeout time.Duration) *ExecResult {
+	startTime := time.Now()
+
+	result := &ExecResult{
+		Command:  strings.Join(args, " "),
+		ExitCode: -1,
+	}
+
+	if len(args) == 0 {
+		result.Error = errNoCommand
+
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = execWaitDelay
+
+	err := cmd.Run()
+
+	result.Duration = time.Since(startTime)
+	result.Output = strings.TrimSpace(output.String())
+
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if ctx.Err() != nil {
+		result.Error = errors.Wrapf(ctx.Err(), "command timeout %s", timeout)
+
+		return result
+	}
+
+	if err != nil {
+		result.Error = errors.Wrap(err, "error in cmd.Run")
+	}
+
+	return result
+}
+
+// returns event as environment variables.
+func eventEnv(resourceName string, event types.ScheduledEventsEvent) []string {
+	return []string{
+		"AZURE_RESOURCE_NAME=" + resourceName,
+		"AZURE_EVENT_ID=" + event.EventId,
+		"AZURE_EVENT_TYPE=" + string(event.EventType),
+		"AZURE_EVENT_STATUS=" + string(event.EventStatus),
+		"AZURE_EVENT_RESOURCE_TYPE=" + event.ResourceType,
+		"AZURE_EVENT_RESOURCES=" + strings.Join(event.Resources, ","),
+		"AZURE_EVENT_NOT_BEFORE=" + event.NotBeforeString(),
+		"AZURE_EVENT_DESCRIPTION=" + event.Description,
+		"AZURE_EVENT_SOURCE=" + event.EventSource,
+		"AZURE_EVENT_DURATION_SECONDS=" + strconv.Itoa(event.DurationInSeconds),
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hooks_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/hooks"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExecHooks(t *testing.T) {
+	t.Parallel()
+
+	script := filepath.Join(t.TempDir(), "hook.sh")
+
+	err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$AZURE_EVENT_TYPE $AZURE_RESOURCE_NAME\"\necho \"$1\"\ncat\n"), 0o700) //nolint:mnd
+	require.NoError(t, err)
+
+	event := types.ScheduledEventsEvent{
+		EventId:   "test-event-id",
+		EventType: types.EventTypePreempt,
+	}
+
+	commands := [][]string{
+		{script, "argument with spaces, and comma"},
+		{"false"},
+		{"sleep", "5"},
+		{"/not/exists"},
+		{},
+	}
+
+	results := hooks.RunExecHooks(context.TODO(), commands, "test-vm", event, time.Second)
+	require.Len(t, results, len(commands))
+
+	require.NoError(t, results[0].Error)
+	require.Equal(t, 0, results[0].ExitCode)
+	require.Contains(t, results[0].Output, "Preempt test-vm")
+	require.Contains(t, results[0].Output, `"EventId":"test-event-id"`)
+	require.Contains(t, results[0].Output, "argument with spaces, and comma")
+
+	require.Error(t, results[1].Error)
+	require.Error(t, results[2].Error)
+	require.Error(t, results[3].Error)
+	require.Error(t, results[4].Error)
+}
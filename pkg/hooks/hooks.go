@@ -0,0 +1,209 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/metrics"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// port of pod hook, pod is opted in if annotation is present
+	AnnotationPort = "aks-node-termination-handler/hook-port"
+	// path of pod hook, default is /
+	AnnotationPath = "aks-node-termination-handler/hook-path"
+	// timeout of pod hook, default is hooks timeout
+	AnnotationTimeout = "aks-node-termination-handler/hook-timeout"
+)
+
+const (
+	// time that is left for drain after hooks, before event starts
+	DrainBudget = 15 * time.Second
+	// Preempt event is sent about 30 seconds before virtual machine is deleted
+	PreemptTimeout = 5 * time.Second
+)
+
+var httpClient = &http.Client{
+	Transport: metrics.NewInstrumenter("hooks").InstrumentedRoundTripper(),
+}
+
+var (
+	errHTTPNotOK = errors.New("http result not OK")
+	errNoPodIP   = errors.New("pod has no IP")
+)
+
+type Result struct {
+	Pod        string
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+	Error      error
+}
+
+func (r *Result) String() string {
+	if r.Error != nil {
+		return fmt.Sprintf("Pod %s hook %s failed in %s: %s", r.Pod, r.URL, r.Duration.Round(time.Millisecond), r.Error.Error())
+	}
+
+	return fmt.Sprintf("Pod %s hook %s returned %d in %s", r.Pod, r.URL, r.StatusCode, r.Duration.Round(time.Millisecond))
+}
+
+// EventTimeout returns time that hooks can wait from now, hooks must not use drainTime before event starts,
+// that is reserved for drain, at least DrainBudget is reserved.
+func EventTimeout(event types.ScheduledEventsEvent, timeout time.Duration, drainTime time.Duration) time.Duration {
+	if event.EventType == types.EventTypePreempt {
+		timeout = min(timeout, PreemptTimeout)
+	}
+
+	return max(min(timeout, event.TimeUntilStart()-max(drainTime, DrainBudget)), 0)
+}
+
+// RunPodHooks sends event to all pods that are opted in with annotations,
+// and waits for all hooks, but not longer than timeout.
+func RunPodHooks(ctx context.Context, pods []corev1.Pod, event types.ScheduledEventsEvent, timeout time.Duration) []*Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.WithError(err).Error("error in json.Marshal")
+
+		return nil
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		results = make([]*Result, 0)
+	)
+
+	for _, pod := range pods {
+		if _, ok := pod.Annotations[AnnotationPort]; !ok {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(pod corev1.Pod) {
+			defer wg.Done()
+
+			result := runPodHook(ctx, pod, body, timeout)
+
+			log.Info(result.String())
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			results = append(results, result)
+		}(pod)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+func runPodHook(ctx context.Context, pod corev1.Pod, body []byte, timeout time.Duration) *Result {
+	startTime := time.Now()
+
+	result := &Result{
+		Pod: fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+	}
+
+	hookURL, hookTimeout, err := getPodHook(pod, timeout)
+	if err != nil {
+		result.Error = err
+
+		return result
+	}
+
+	result.URL = hookURL
+
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hookURL, bytes.NewReader(body))
+	if err != nil {
+		result.Error = errors.Wrap(err, "error in http.NewRequestWithContext")
+
+		return result
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+
+	result.Duration = time.Since(startTime)
+
+	if err != nil {
+		result.Error = errors.Wrap(err, "error in client.Do")
+
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = errors.Wrap(errHTTPNotOK, fmt.Sprintf("StatusCode=%d", resp.StatusCode))
+	}
+
+	return result
+}
+
+// returns hook url and timeout from pod annotations.
+func getPodHook(pod corev1.Pod, timeout time.Duration) (string, time.Duration, error) {
+	if len(pod.Status.PodIP) == 0 {
+		return "", 0, errNoPodIP
+	}
+
+	port, err := strconv.Atoi(pod.Annotations[AnnotationPort])
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "invalid annotation %s", AnnotationPort)
+	}
+
+	path := pod.Annotations[AnnotationPath]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	hookTimeout := timeout
+
+	if value, ok := pod.Annotations[AnnotationTimeout]; ok {
+		podTimeout, err := time.ParseDuration(value)
+		if err != nil {
+			return "", 0, errors.Wrapf(err, "invalid annotation %s", AnnotationTimeout)
+		}
+
+		// pod can not wait longer than hooks timeout
+		hookTimeout = min(podTimeout, timeout)
+	}
+
+	hookURL := fmt.Sprintf("http://%s%s", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(port)), path)
+
+	return hookURL, hookTimeout, nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/hooks"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunPodHooks(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/flush", func(w http.ResponseWriter, r *http.Request) {
+		event := types.ScheduledEventsEvent{}
+
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil || event.EventId != "test-event-id" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("OK"))
+	})
+	handler.HandleFunc("/error", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		utils.SleepWithContext(r.Context(), 5*time.Second)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	serverURL, err := url.Parse(testServer.URL)
+	require.NoError(t, err)
+
+	host, port, err := net.SplitHostPort(serverURL.Host)
+	require.NoError(t, err)
+
+	newPod := func(name string, annotations map[string]string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "test",
+				Name:        name,
+				Annotations: annotations,
+			},
+			Status: corev1.PodStatus{
+				PodIP: host,
+			},
+		}
+	}
+
+	pods := []corev1.Pod{
+		newPod("not-opted-in", nil),
+		newPod("flush", map[string]string{
+			hooks.AnnotationPort: port,
+			hooks.AnnotationPath: "/flush",
+		}),
+		newPod("error", map[string]string{
+			hooks.AnnotationPort: port,
+			hooks.AnnotationPath: "error",
+		}),
+		newPod("slow", map[string]string{
+			hooks.AnnotationPort:    port,
+			hooks.AnnotationPath:    "/slow",
+			hooks.AnnotationTimeout: "1s",
+		}),
+		newPod("invalid-port", map[string]string{
+			hooks.AnnotationPort: "invalid",
+		}),
+	}
+
+	event := types.ScheduledEventsEvent{
+		EventId:   "test-event-id",
+		EventType: types.EventTypePreempt,
+	}
+
+	startTime := time.Now()
+
+	results := hooks.RunPodHooks(context.TODO(), pods, event, 3*time.Second)
+
+	require.Less(t, time.Since(startTime), 3*time.Second)
+	require.Len(t, results, 4)
+
+	for _, result := range results {
+		t.Log(result.String())
+
+		switch result.Pod {
+		case "test/flush":
+			require.NoError(t, result.Error)
+			require.Equal(t, http.StatusOK, result.StatusCode)
+		case "test/error":
+			require.Error(t, result.Error)
+			require.Equal(t, http.StatusInternalServerError, result.StatusCode)
+		case "test/slow", "test/invalid-port":
+			require.Error(t, result.Error)
+		default:
+			t.Fatalf("unexpected pod %s", result.Pod)
+		}
+	}
+}
+
+func TestEventTimeout(t *testing.T) {
+	t.Parallel()
+
+	newEvent := func(eventType types.ScheduledEventsEventType, untilStart time.Duration) types.ScheduledEventsEvent {
+		notBefore := time.Now().Add(untilStart)
+
+		return types.ScheduledEventsEvent{EventType: eventType, NotBefore: &notBefore}
+	}
+
+	timeout := 60 * time.Second
+
+	assert.Equal(t, timeout, hooks.EventTimeout(newEvent(types.EventTypeReboot, time.Hour), timeout, 0))
+	assert.Equal(t, hooks.PreemptTimeout, hooks.EventTimeout(newEvent(types.EventTypePreempt, 30*time.Second), timeout, 0))
+	assert.InDelta(t, 5*time.Second, hooks.EventTimeout(newEvent(types.EventTypeTerminate, hooks.DrainBudget+5*time.Second), timeout, 0), float64(time.Second))
+	assert.Zero(t, hooks.EventTimeout(newEvent(types.EventTypePreempt, 10*time.Second), timeout, 0))
+	// event without NotBefore has already started
+	assert.Zero(t, hooks.EventTimeout(types.ScheduledEventsEvent{EventType: types.EventTypeReboot}, timeout, 0))
+	// time that is reserved for drain after waiting for lease is not used by hooks
+	assert.InDelta(t, 10*time.Second, hooks.EventTimeout(newEvent(types.EventTypeReboot, 130*time.Second), timeout, 120*time.Second), float64(time.Second))
+	assert.Zero(t, hooks.EventTimeout(newEvent(types.EventTypeReboot, 120*time.Second), timeout, 120*time.Second))
+}
+
+func TestRunPodHooksBeforeNotBefore(t *testing.T) {
+	t.Parallel()
+
+	// handler is stopped before server is closed
+	done := make(chan struct{})
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+	defer close(done)
+
+	serverURL, err := url.Parse(testServer.URL)
+	require.NoError(t, err)
+
+	host, port, err := net.SplitHostPort(serverURL.Host)
+	require.NoError(t, err)
+
+	pods := []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "test",
+			Name:        "slow",
+			Annotations: map[string]string{hooks.AnnotationPort: port, hooks.AnnotationPath: "/slow"},
+		},
+		Status: corev1.PodStatus{PodIP: host},
+	}}
+
+	notBefore := time.Now().Add(hooks.DrainBudget + 2*time.Second)
+	event := types.ScheduledEventsEvent{
+		EventId:   "test-event-id",
+		EventType: types.EventTypeTerminate,
+		NotBefore: &notBefore,
+	}
+
+	results := hooks.RunPodHooks(context.TODO(), pods, event, hooks.EventTimeout(event, time.Minute, 0))
+
+	require.Len(t, results, 1)
+	require.Error(t, results[0].Error)
+
+	// drain starts with budget before event starts
+	assert.GreaterOrEqual(t, time.Until(notBefore), hooks.DrainBudget-time.Second)
+}
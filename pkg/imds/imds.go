@@ -0,0 +1,179 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package imds
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const requestTimeout = 10 * time.Second
+
+var httpClient = &http.Client{
+	Transport: metrics.NewInstrumenter("imds").InstrumentedRoundTripper(),
+}
+
+var (
+	errHTTPNotOK  = errors.New("http result not OK")
+	errNoInstance = errors.New("instance name is empty")
+)
+
+// https://learn.microsoft.com/en-us/azure/virtual-machines/instance-metadata-service
+type Compute struct {
+	Name              string `json:"name"`
+	VMScaleSetName    string `json:"vmScaleSetName"`
+	ResourceID        string `json:"resourceId"`
+	SubscriptionID    string `json:"subscriptionId"`
+	ResourceGroupName string `json:"resourceGroupName"`
+	Location          string `json:"location"`
+	Zone              string `json:"zone"`
+	VMSize            string `json:"vmSize"`
+	Priority          string `json:"priority"`
+	EvictionPolicy    string `json:"evictionPolicy"`
+	OsType            string `json:"osType"`
+}
+
+// compute metadata of current virtual machine, it's loaded on first use.
+var (
+	computeEndpoint string
+	computeLoaded   bool
+	compute         *Compute
+	computeMutex    sync.Mutex
+)
+
+// SetEndpoint sets endpoint of instance metadata, empty endpoint disables metadata.
+func SetEndpoint(endpoint string) {
+	computeMutex.Lock()
+	defer computeMutex.Unlock()
+
+	computeEndpoint = endpoint
+	computeLoaded = false
+	compute = nil
+}
+
+// Get returns compute metadata of current virtual machine, metadata is requested only once,
+// returns nil if metadata is not available.
+func Get(ctx context.Context) *Compute {
+	computeMutex.Lock()
+	defer computeMutex.Unlock()
+
+	if computeLoaded || len(computeEndpoint) == 0 {
+		return compute
+	}
+
+	computeLoaded = true
+
+	result, err := GetCompute(ctx, computeEndpoint)
+	if err != nil {
+		log.WithError(err).Warn("instance metadata is not available")
+
+		return nil
+	}
+
+	compute = result
+
+	return compute
+}
+
+// EventResourceName returns name of resource in scheduled events,
+// for virtual machine scale sets instance name is <vmScaleSetName>_<instanceId>.
+func (c *Compute) EventResourceName() string {
+	return c.Name
+}
+
+// GetCompute returns compute metadata of current virtual machine.
+func GetCompute(ctx context.Context, endpoint string) (*Compute, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in http.NewRequestWithContext")
+	}
+
+	req.Header.Add("Metadata", "true")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in client.Do(req)")
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in io.ReadAll")
+	}
+
+	log.Debugf("instance metadata: %s", string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(errHTTPNotOK, "StatusCode=%d", resp.StatusCode)
+	}
+
+	result := Compute{}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.Wrap(err, "error in json.Unmarshal")
+	}
+
+	if len(result.Name) == 0 {
+		return nil, errNoInstance
+	}
+
+	return &result, nil
+}
+
+type versionsType struct {
+	APIVersions []string `json:"apiVersions"`
+}
+
+// GetAPIVersions returns api versions that are supported by IMDS.
+func GetAPIVersions(ctx context.Context, endpoint string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in http.NewRequestWithContext")
+	}
+
+	req.Header.Add("Metadata", "true")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in client.Do(req)")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(errHTTPNotOK, "StatusCode=%d", resp.StatusCode)
+	}
+
+	versions := versionsType{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, errors.Wrap(err, "error in json.Decode")
+	}
+
+	return versions.APIVersions, nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package imds_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/imds"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCompute(t *testing.T) {
+	t.Parallel()
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/vmss", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"name":"aks-pool-12345678-vmss_5","vmScaleSetName":"aks-pool-12345678-vmss","vmSize":"Standard_D4s_v3","zone":"1"}`))
+	})
+	handler.HandleFunc("/empty", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	handler.HandleFunc("/error", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	compute, err := imds.GetCompute(context.TODO(), testServer.URL+"/vmss")
+	require.NoError(t, err)
+	require.Equal(t, "aks-pool-12345678-vmss_5", compute.EventResourceName())
+	require.Equal(t, "Standard_D4s_v3", compute.VMSize)
+
+	_, err = imds.GetCompute(context.TODO(), testServer.URL+"/empty")
+	require.Error(t, err)
+
+	_, err = imds.GetCompute(context.TODO(), testServer.URL+"/error")
+	require.Error(t, err)
+}
+
+//nolint:paralleltest
+func TestGet(t *testing.T) {
+	var requests atomic.Int32
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+
+		_, _ = w.Write([]byte(`{"name":"aks-pool-12345678-vmss_5"}`))
+	}))
+	defer testServer.Close()
+
+	// metadata is disabled
+	imds.SetEndpoint("")
+	require.Nil(t, imds.Get(context.TODO()))
+
+	// metadata is not requested until it's used
+	imds.SetEndpoint(testServer.URL)
+	require.Equal(t, int32(0), requests.Load())
+
+	require.Equal(t, "aks-pool-12345678-vmss_5", imds.Get(context.TODO()).Name)
+	require.Equal(t, "aks-pool-12345678-vmss_5", imds.Get(context.TODO()).Name)
+	require.Equal(t, int32(1), requests.Load())
+
+	imds.SetEndpoint("")
+}
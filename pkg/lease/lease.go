@@ -0,0 +1,268 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package lease
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrorrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	defaultDuration      = 60 * time.Second
+	defaultRetryInterval = 5 * time.Second
+)
+
+// Semaphore limits number of holders with Kubernetes leases,
+// every lease with name <Name>-<index> is one slot of semaphore.
+type Semaphore struct {
+	Client kubernetes.Interface
+	// namespace of leases
+	Namespace string
+	// prefix of leases names
+	Name string
+	// maximum number of holders
+	Size int
+	// identity of holder
+	Identity string
+	// lease will expire if holder will not renew it
+	Duration time.Duration
+	// interval between attempts to acquire lease
+	RetryInterval time.Duration
+}
+
+func NewSemaphore(client kubernetes.Interface, namespace, name string, size int, identity string) *Semaphore {
+	return &Semaphore{
+		Client:        client,
+		Namespace:     namespace,
+		Name:          name,
+		Size:          size,
+		Identity:      identity,
+		Duration:      defaultDuration,
+		RetryInterval: defaultRetryInterval,
+	}
+}
+
+// Acquire waits until one of the leases is acquired or timeout is reached, errors of acquiring
+// are retried until timeout, lease is renewed until returned function is called.
+func (s *Semaphore) Acquire(ctx context.Context, timeout time.Duration) (func(), error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		leaseName, err := s.TryAcquire(waitCtx)
+
+		switch {
+		case err != nil:
+			// errors of API server are transient, only timeout stops waiting
+			log.WithError(err).Warnf("error acquiring lease %s, retrying in %s", s.Name, s.RetryInterval)
+		case len(leaseName) > 0:
+			return s.hold(ctx, leaseName), nil
+		default:
+			log.Debugf("All %d leases %s are held, waiting %s", s.Size, s.Name, s.RetryInterval)
+		}
+
+		utils.SleepWithContext(waitCtx, s.RetryInterval)
+
+		if waitCtx.Err() != nil {
+			return nil, errors.Wrap(waitCtx.Err(), "lease is not acquired")
+		}
+	}
+}
+
+// TryAcquire tries to acquire one of the leases, returns name of acquired lease
+// or empty string if all leases are held.
+func (s *Semaphore) TryAcquire(ctx context.Context) (string, error) {
+	for i := range s.Size {
+		leaseName := fmt.Sprintf("%s-%d", s.Name, i)
+
+		acquired, err := s.tryAcquireLease(ctx, leaseName)
+		if err != nil {
+			return "", errors.Wrapf(err, "error acquiring lease %s", leaseName)
+		}
+
+		if acquired {
+			log.Infof("Lease %s/%s acquired by %s", s.Namespace, leaseName, s.Identity)
+
+			return leaseName, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (s *Semaphore) tryAcquireLease(ctx context.Context, leaseName string) (bool, error) {
+	leases := s.Client.CoordinationV1().Leases(s.Namespace)
+	now := metav1.NewMicroTime(time.Now())
+
+	lease, err := leases.Get(ctx, leaseName, metav1.GetOptions{})
+	if apierrorrs.IsNotFound(err) {
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      leaseName,
+				Namespace: s.Namespace,
+			},
+			Spec: s.leaseSpec(now),
+		}, metav1.CreateOptions{})
+
+		switch {
+		case err == nil:
+			return true, nil
+		case apierrorrs.IsAlreadyExists(err):
+			return false, nil
+		default:
+			return false, errors.Wrap(err, "error in leases.create")
+		}
+	}
+
+	if err != nil {
+		return false, errors.Wrap(err, "error in leases.get")
+	}
+
+	if !s.isAvailable(lease, now.Time) {
+		return false, nil
+	}
+
+	lease.Spec = s.leaseSpec(now)
+
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+
+	switch {
+	case err == nil:
+		return true, nil
+	case apierrorrs.IsConflict(err):
+		return false, nil
+	default:
+		return false, errors.Wrap(err, "error in leases.update")
+	}
+}
+
+// lease is available if it has no holder, it's expired or it's already held by this identity.
+func (s *Semaphore) isAvailable(lease *coordinationv1.Lease, now time.Time) bool {
+	holder := ptr.Deref(lease.Spec.HolderIdentity, "")
+
+	if len(holder) == 0 || holder == s.Identity {
+		return true
+	}
+
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	expireTime := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+
+	return now.After(expireTime)
+}
+
+func (s *Semaphore) leaseSpec(now metav1.MicroTime) coordinationv1.LeaseSpec {
+	return coordinationv1.LeaseSpec{
+		HolderIdentity:       ptr.To(s.Identity),
+		LeaseDurationSeconds: ptr.To(int32(s.Duration.Seconds())),
+		AcquireTime:          &now,
+		RenewTime:            &now,
+	}
+}
+
+// hold renews lease until returned function is called.
+func (s *Semaphore) hold(ctx context.Context, leaseName string) func() {
+	holdCtx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for {
+			utils.SleepWithContext(holdCtx, s.Duration/3) //nolint:mnd
+
+			if holdCtx.Err() != nil {
+				return
+			}
+
+			if err := s.renew(holdCtx, leaseName); err != nil {
+				log.WithError(err).Errorf("error renewing lease %s", leaseName)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+
+		// lease must be released even if parent context is canceled
+		releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), s.RetryInterval)
+		defer releaseCancel()
+
+		if err := s.release(releaseCtx, leaseName); err != nil {
+			log.WithError(err).Errorf("error releasing lease %s", leaseName)
+		}
+	}
+}
+
+func (s *Semaphore) renew(ctx context.Context, leaseName string) error {
+	leases := s.Client.CoordinationV1().Leases(s.Namespace)
+
+	lease, err := leases.Get(ctx, leaseName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "error in leases.get")
+	}
+
+	if ptr.Deref(lease.Spec.HolderIdentity, "") != s.Identity {
+		return errors.Errorf("lease is held by %s", ptr.Deref(lease.Spec.HolderIdentity, ""))
+	}
+
+	lease.Spec.RenewTime = ptr.To(metav1.NewMicroTime(time.Now()))
+
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, "error in leases.update")
+	}
+
+	return nil
+}
+
+func (s *Semaphore) release(ctx context.Context, leaseName string) error {
+	leases := s.Client.CoordinationV1().Leases(s.Namespace)
+
+	lease, err := leases.Get(ctx, leaseName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "error in leases.get")
+	}
+
+	if ptr.Deref(lease.Spec.HolderIdentity, "") != s.Identity {
+		return nil
+	}
+
+	lease.Spec.HolderIdentity = nil
+	lease.Spec.AcquireTime = nil
+	lease.Spec.RenewTime = nil
+
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, "error in leases.update")
+	}
+
+	log.Infof("Lease %s/%s released by %s", s.Namespace, leaseName, s.Identity)
+
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package lease_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/lease"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSemaphore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	client := fake.NewClientset()
+
+	node1 := lease.NewSemaphore(client, "test", "drain", 1, "node1")
+	node2 := lease.NewSemaphore(client, "test", "drain", 1, "node2")
+	node2.RetryInterval = 100 * time.Millisecond
+
+	release, err := node1.Acquire(ctx, time.Second)
+	require.NoError(t, err)
+
+	// lease is held by node1
+	leaseName, err := node2.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.Empty(t, leaseName)
+
+	_, err = node2.Acquire(ctx, time.Second)
+	require.Error(t, err)
+
+	release()
+
+	// lease is released by node1
+	leaseName, err = node2.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "drain-0", leaseName)
+}
+
+func TestSemaphoreExpired(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	client := fake.NewClientset()
+
+	node1 := lease.NewSemaphore(client, "test", "drain", 1, "node1")
+	node1.Duration = time.Second
+
+	node2 := lease.NewSemaphore(client, "test", "drain", 1, "node2")
+
+	leaseName, err := node1.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "drain-0", leaseName)
+
+	// lease is not renewed by node1
+	time.Sleep(2 * time.Second)
+
+	leaseName, err = node2.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "drain-0", leaseName)
+}
+
+func TestSemaphoreRetry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	client := fake.NewClientset()
+
+	// API server is not available
+	failures := 2
+
+	client.PrependReactor("get", "leases", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		if failures == 0 {
+			return false, nil, nil
+		}
+
+		failures--
+
+		return true, nil, apierrors.NewInternalError(context.DeadlineExceeded)
+	})
+
+	node1 := lease.NewSemaphore(client, "test", "drain", 1, "node1")
+	node1.RetryInterval = 10 * time.Millisecond
+
+	release, err := node1.Acquire(ctx, time.Second)
+	require.NoError(t, err)
+	require.Zero(t, failures)
+
+	release()
+
+	// errors are retried until timeout
+	failures = 1000
+
+	_, err = node1.Acquire(ctx, 100*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -100,7 +100,7 @@ func (i *Instrumenter) InstrumentedRoundTripper() http.RoundTripper {
 	}
 
 	if i.proxyURL != nil {
-		log.Infof("using http_proxy %s for %s", i.proxyURL.String(), i.subsystemIdentifier)
+		log.Infof("using http_proxy %s for %s", i.proxyURL.Redacted(), i.subsystemIdentifier)
 
 		defaultTransport.Proxy = http.ProxyURL(i.proxyURL)
 	}
@@ -133,6 +133,33 @@ var ErrorReadingEndpoint = promauto.NewCounterVec(
 	[]string{"node", "resource"},
 )
 
+var ErrorProcessingEvents = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "error_processing_events_total",
+		Help:      "A counter for errors of processing events that were read from endpoint",
+	},
+	[]string{"node", "resource"},
+)
+
+var ReadEventsConsecutiveFailures = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "read_events_consecutive_failures",
+		Help:      "Number of consecutive failed reads of endpoint",
+	},
+	[]string{"node", "resource"},
+)
+
+var ReadEventsThrottledTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "read_events_throttled_total",
+		Help:      "A counter for reads of endpoint throttled by IMDS",
+	},
+	[]string{"node", "resource"},
+)
+
 var ScheduledEventsTotal = promauto.NewCounterVec(
 	prometheus.CounterOpts{
 		Namespace: namespace,
@@ -142,6 +169,132 @@ var ScheduledEventsTotal = promauto.NewCounterVec(
 	[]string{"node", "resource", "type"},
 )
 
+var DrainLeaseWaiting = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "drain_lease_waiting",
+		Help:      "Node is waiting for drain lease",
+	},
+)
+
+var DrainLeaseTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drain_lease_total",
+		Help:      "A counter for drain lease acquisition results",
+	},
+	[]string{"result"},
+)
+
+var BuildInfo = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "A metric with a constant '1' value labeled by version and configuration of handler",
+	},
+	[]string{"version", "go_version", "mode", "dry_run", "taint_effect", "eviction_mode", "telegram", "webhook"},
+)
+
+var ConfigReloadTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reload_total",
+		Help:      "A counter for reloads of config file by result",
+	},
+	[]string{"result"},
+)
+
+var ReadEventsLastSuccessTimestamp = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "read_events_last_success_timestamp_seconds",
+		Help:      "Unix time of last successful read of endpoint",
+	},
+	[]string{"node", "resource"},
+)
+
+var ReadEventsPeriodSeconds = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "read_events_period_seconds",
+		Help:      "Configured interval of reading endpoint",
+	},
+	[]string{"node", "resource"},
+)
+
+var NodeDraining = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "node_draining",
+		Help:      "Number of drains of node in progress",
+	},
+)
+
+var DrainTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drain_total",
+		Help:      "A counter for node drains by outcome (success, timeout, error, dry-run)",
+	},
+	[]string{"outcome"},
+)
+
+var DrainDurationSeconds = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "drain_duration_seconds",
+		Help:      "The duration in seconds of node drain",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 900},
+	},
+	[]string{"outcome"},
+)
+
+var DrainPodsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drain_pods_total",
+		Help:      "A counter for pods removed from node by result (evicted, deleted, failed)",
+	},
+	[]string{"namespace", "result"},
+)
+
+var EventLeadTimeSeconds = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "event_lead_time_seconds",
+		Help:      "The time in seconds from receipt of event to NotBefore, 0 if event has already started",
+		Buckets:   []float64{0, 5, 15, 30, 60, 120, 300, 600, 900},
+	},
+	[]string{"type"},
+)
+
+var TaintOperationsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "taint_operations_total",
+		Help:      "A counter for node taint operations by result",
+	},
+	[]string{"operation", "result"},
+)
+
+var NotificationsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "A counter for sent notifications by transport and result",
+	},
+	[]string{"transport", "result"},
+)
+
+// ResultLabel returns value of result label.
+func ResultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "success"
+}
+
 var KubernetesAPIRequest = promauto.NewCounterVec(prometheus.CounterOpts{
 	Namespace: namespace,
 	Name:      "apiserver_request_total",
@@ -125,3 +125,15 @@ func TestInstrumenterBabProxy(t *testing.T) {
 	}
 	defer r.Body.Close()
 }
+
+func TestResultLabel(t *testing.T) {
+	t.Parallel()
+
+	if label := metrics.ResultLabel(nil); label != "success" {
+		t.Fatalf("unexpected label %s", label)
+	}
+
+	if label := metrics.ResultLabel(io.EOF); label != "error" {
+		t.Fatalf("unexpected label %s", label)
+	}
+}
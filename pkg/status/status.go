@@ -0,0 +1,208 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+)
+
+// maximum number of records that are kept in memory.
+const maxRecords = 50
+
+type Outcome string
+
+const (
+	OutcomeReceived     Outcome = "Received"
+	OutcomeExcluded     Outcome = "Excluded"
+	OutcomeWaitingLease Outcome = "WaitingLease"
+	OutcomeDraining     Outcome = "Draining"
+	OutcomeDrained      Outcome = "Drained"
+	OutcomeFailed       Outcome = "Failed"
+	OutcomeHooksRun     Outcome = "HooksRun"
+	OutcomeDryRun       Outcome = "DryRun"
+)
+
+// outcomes of events that are not finished yet.
+var pendingOutcomes = []Outcome{
+	OutcomeReceived,
+	OutcomeWaitingLease,
+	OutcomeDraining,
+}
+
+type EventRecord struct {
+	Event      types.ScheduledEventsEvent `json:"event"`
+	Outcome    Outcome                    `json:"outcome"`
+	Error      string                     `json:"error,omitempty"`
+	ReceivedAt time.Time                  `json:"receivedAt"`
+	UpdatedAt  time.Time                  `json:"updatedAt"`
+	// pods that were evicted or failed to evict while draining node
+	EvictedPods []string `json:"evictedPods,omitempty"`
+	Failures    []string `json:"failures,omitempty"`
+}
+
+// IsPending returns true if event processing is not finished.
+func (e *EventRecord) IsPending() bool {
+	return slices.Contains(pendingOutcomes, e.Outcome)
+}
+
+type NotificationRecord struct {
+	Transport string    `json:"transport"`
+	EventID   string    `json:"eventId"`
+	Time      time.Time `json:"time"`
+	Error     string    `json:"error,omitempty"`
+}
+
+var (
+	mutex         sync.RWMutex
+	events        = make([]*EventRecord, 0)
+	notifications = make([]*NotificationRecord, 0)
+)
+
+// RecordEvent records that event was received.
+func RecordEvent(event types.ScheduledEventsEvent) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+
+	events = append(events, &EventRecord{
+		Event:      event,
+		Outcome:    OutcomeReceived,
+		ReceivedAt: now,
+		UpdatedAt:  now,
+	})
+
+	if len(events) > maxRecords {
+		events = events[len(events)-maxRecords:]
+	}
+}
+
+// SetEventOutcome updates outcome of received event.
+func SetEventOutcome(eventID string, outcome Outcome, err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Event.EventId != eventID {
+			continue
+		}
+
+		events[i].Outcome = outcome
+		events[i].UpdatedAt = time.Now()
+
+		if err != nil {
+			events[i].Error = err.Error()
+		}
+
+		return
+	}
+}
+
+// SetEventPods records pods that were evicted or failed to evict while draining node for event.
+func SetEventPods(eventID string, evictedPods []string, failures []string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Event.EventId != eventID {
+			continue
+		}
+
+		events[i].EvictedPods = slices.Clone(evictedPods)
+		events[i].Failures = slices.Clone(failures)
+		events[i].UpdatedAt = time.Now()
+
+		return
+	}
+}
+
+// GetEvent returns copy of received event, false if event is not recorded.
+func GetEvent(eventID string) (EventRecord, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Event.EventId == eventID {
+			return *events[i], true
+		}
+	}
+
+	return EventRecord{}, false
+}
+
+// RecordNotification records result of notification delivery.
+func RecordNotification(transport string, eventID string, err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	record := &NotificationRecord{
+		Transport: transport,
+		EventID:   eventID,
+		Time:      time.Now(),
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	notifications = append(notifications, record)
+
+	if len(notifications) > maxRecords {
+		notifications = notifications[len(notifications)-maxRecords:]
+	}
+}
+
+// Events returns copy of received events, newest is last.
+func Events() []EventRecord {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	result := make([]EventRecord, 0, len(events))
+
+	for _, event := range events {
+		result = append(result, *event)
+	}
+
+	return result
+}
+
+// PendingEvents returns copy of events that are not finished yet.
+func PendingEvents() []EventRecord {
+	result := make([]EventRecord, 0)
+
+	for _, event := range Events() {
+		if event.IsPending() {
+			result = append(result, event)
+		}
+	}
+
+	return result
+}
+
+// Notifications returns copy of notifications results, newest is last.
+func Notifications() []NotificationRecord {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	result := make([]NotificationRecord, 0, len(notifications))
+
+	for _, notification := range notifications {
+		result = append(result, *notification)
+	}
+
+	return result
+}
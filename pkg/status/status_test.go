@@ -0,0 +1,67 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/status"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest
+func TestStatus(t *testing.T) {
+	for range 60 {
+		status.RecordEvent(types.ScheduledEventsEvent{EventId: "old-event"})
+	}
+
+	status.RecordEvent(types.ScheduledEventsEvent{EventId: "drained-event"})
+	status.RecordEvent(types.ScheduledEventsEvent{EventId: "failed-event"})
+	status.RecordEvent(types.ScheduledEventsEvent{EventId: "pending-event"})
+
+	status.SetEventOutcome("drained-event", status.OutcomeDrained, nil)
+	status.SetEventOutcome("failed-event", status.OutcomeFailed, errors.New("test error")) //nolint:err113
+	status.SetEventOutcome("pending-event", status.OutcomeWaitingLease, nil)
+
+	events := status.Events()
+	require.Len(t, events, 50)
+
+	last := events[len(events)-3:]
+	require.Equal(t, status.OutcomeDrained, last[0].Outcome)
+	require.Equal(t, "test error", last[1].Error)
+	require.Equal(t, status.OutcomeWaitingLease, last[2].Outcome)
+
+	status.SetEventPods("failed-event", []string{"default/pod1"}, []string{"default/pod2: test error"})
+
+	record, ok := status.GetEvent("failed-event")
+	require.True(t, ok)
+	require.Equal(t, []string{"default/pod1"}, record.EvictedPods)
+	require.Equal(t, []string{"default/pod2: test error"}, record.Failures)
+
+	_, ok = status.GetEvent("unknown-event")
+	require.False(t, ok)
+
+	pending := status.PendingEvents()
+	require.Len(t, pending, 48)
+	require.Equal(t, "pending-event", pending[len(pending)-1].Event.EventId)
+
+	status.RecordNotification("telegram", "drained-event", nil)
+	status.RecordNotification("webhook", "drained-event", errors.New("test error")) //nolint:err113
+
+	notifications := status.Notifications()
+	require.Len(t, notifications, 2)
+	require.Empty(t, notifications[0].Error)
+	require.Equal(t, "test error", notifications[1].Error)
+}
@@ -18,6 +18,8 @@ import (
 	"html/template"
 
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/api"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/imds"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
 	"github.com/pkg/errors"
 )
@@ -35,9 +37,47 @@ type MessageType struct {
 	NodeRegion   string            `description:"Node label topology.kubernetes.io/region"`
 	NodeZone     string            `description:"Node label topology.kubernetes.io/zone"`
 	NodePods     []string          `description:"List of pods on node"`
+	Simulated    bool              `description:"Event is simulated and is not from Azure"`
+	// instance metadata, empty if metadata is not available
+	InstanceName           string `description:"Instance metadata compute.name"`
+	InstanceScaleSet       string `description:"Instance metadata compute.vmScaleSetName"`
+	InstanceSize           string `description:"Instance metadata compute.vmSize"`
+	InstancePriority       string `description:"Instance metadata compute.priority"`
+	InstanceEvictionPolicy string `description:"Instance metadata compute.evictionPolicy"`
+	InstanceZone           string `description:"Instance metadata compute.zone"`
+	InstanceResourceID     string `description:"Instance metadata compute.resourceId"`
+}
+
+// adds instance metadata to message.
+func (m *MessageType) withInstance(ctx context.Context) *MessageType {
+	compute := imds.Get(ctx)
+	if compute == nil {
+		return m
+	}
+
+	m.InstanceName = compute.Name
+	m.InstanceScaleSet = compute.VMScaleSetName
+	m.InstanceSize = compute.VMSize
+	m.InstancePriority = compute.Priority
+	m.InstanceEvictionPolicy = compute.EvictionPolicy
+	m.InstanceZone = compute.Zone
+	m.InstanceResourceID = compute.ResourceID
+
+	return m
 }
 
 func NewMessageType(ctx context.Context, nodeName string, event types.ScheduledEventsEvent) (*MessageType, error) {
+	// there is no node labels and pods without Kubernetes
+	if config.Get().IsStandalone() {
+		message := &MessageType{
+			Event:     event,
+			NodeName:  nodeName,
+			Simulated: event.IsSimulated(),
+		}
+
+		return message.withInstance(ctx), nil
+	}
+
 	nodeLabels, err := api.GetNodeLabels(ctx, nodeName)
 	if err != nil {
 		return nil, errors.Wrap(err, "error in nodes.get")
@@ -48,7 +88,7 @@ func NewMessageType(ctx context.Context, nodeName string, event types.ScheduledE
 		return nil, errors.Wrap(err, "error in getNodePods")
 	}
 
-	return &MessageType{
+	message := &MessageType{
 		Event:        event,
 		NodeName:     nodeName,
 		NodeLabels:   nodeLabels,
@@ -60,7 +100,10 @@ func NewMessageType(ctx context.Context, nodeName string, event types.ScheduledE
 		NodeRegion:   nodeLabels["topology.kubernetes.io/region"],
 		NodeZone:     nodeLabels["topology.kubernetes.io/zone"],
 		NodePods:     nodePods,
-	}, nil
+		Simulated:    event.IsSimulated(),
+	}
+
+	return message.withInstance(ctx), nil
 }
 
 func Message(obj *MessageType) (string, error) {
@@ -20,6 +20,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/template"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
@@ -49,6 +50,29 @@ func TestTemplateMessage(t *testing.T) {
 	}
 }
 
+func TestTemplateEventHelpers(t *testing.T) {
+	t.Parallel()
+
+	notBefore := time.Date(2016, 9, 19, 18, 29, 47, 0, time.UTC)
+
+	obj := &template.MessageType{
+		Event: types.ScheduledEventsEvent{
+			EventStatus: types.EventStatusScheduled,
+			NotBefore:   &notBefore,
+		},
+		Template: "{{ .Event.EventStatus }} {{ .Event.NotBeforeString }} {{ .Event.TimeUntilStart }} {{ .Event.IsImminent 0 }}",
+	}
+
+	tpl, err := template.Message(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "Scheduled Mon, 19 Sep 2016 18:29:47 GMT 0s true"; tpl != want {
+		t.Fatalf("want=%s,got=%s", want, tpl)
+	}
+}
+
 func TestFakeTemplate(t *testing.T) {
 	t.Parallel()
 
@@ -117,9 +141,9 @@ func printType(prefix string, message interface{}) {
 			case reflect.Int:
 				value = fmt.Sprintf("%d", value)
 			case reflect.Map:
-				a := reflect.ValueOf(value).Interface().(map[string]string) //nolint:forcetypeassert
-				for k, v := range a {
-					value = fmt.Sprintf("%s:%s ...", k, v)
+				mapRange := v.Field(i).MapRange()
+				for mapRange.Next() {
+					value = fmt.Sprintf("%v:%v ...", mapRange.Key(), mapRange.Value())
 
 					break
 				}
@@ -0,0 +1,154 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/maksim-paskal/aks-node-termination-handler"
+
+// Init configures OpenTelemetry tracing with OTLP exporter, empty endpoint disables tracing.
+// Returned function flushes and stops exporter.
+func Init(ctx context.Context, endpoint string, sampleRatio float64, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if len(endpoint) == 0 {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, errors.Wrap(err, "error in otlptracehttp.New")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("aks-node-termination-handler"),
+			semconv.ServiceVersion(version),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	log.Infof("tracing enabled, endpoint=%s, sampleRatio=%f", endpoint, sampleRatio)
+
+	return provider.Shutdown, nil
+}
+
+// Start creates span that is child of span in context.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...) //nolint:spancheck
+}
+
+// End records error in span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// LogHook adds trace and span IDs to log entries that are created with context.
+type LogHook struct{}
+
+func (LogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (LogHook) Fire(entry *log.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	spanContext := trace.SpanContextFromContext(entry.Context)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	entry.Data["trace_id"] = spanContext.TraceID().String()
+	entry.Data["span_id"] = spanContext.SpanID().String()
+
+	return nil
+}
+
+// Transport creates span for every request and injects trace context in request headers,
+// retries of request are separate spans.
+type Transport struct {
+	Name string
+	Next http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Start(req.Context(), t.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(utils.RedactURL(req.URL.String())),
+		),
+	)
+
+	req = req.Clone(ctx)
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		// error is recorded in span, URL of request can contain tokens
+		End(span, utils.RedactURLError(err, req.URL.String()))
+
+		return nil, err //nolint:wrapcheck
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, "StatusCode="+strconv.Itoa(resp.StatusCode))
+	}
+
+	span.End()
+
+	return resp, nil
+}
+
+// EventAttributes returns span attributes of scheduled event.
+func EventAttributes(event types.ScheduledEventsEvent) trace.SpanStartEventOption {
+	return trace.WithAttributes(
+		attribute.String("event.id", event.EventId),
+		attribute.String("event.type", string(event.EventType)),
+		attribute.String("event.status", string(event.EventStatus)),
+		attribute.String("event.source", event.EventSource),
+		attribute.String("event.notBefore", event.NotBeforeString()),
+		attribute.StringSlice("event.resources", event.Resources),
+	)
+}
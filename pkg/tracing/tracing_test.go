@@ -0,0 +1,118 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/tracing"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+//nolint:paralleltest
+func TestTracing(t *testing.T) {
+	shutdown, err := tracing.Init(context.TODO(), "", 1, "test")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.TODO()))
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	traceparent := ""
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer testServer.Close()
+
+	ctx, span := tracing.Start(context.TODO(), "test")
+
+	client := &http.Client{
+		Transport: &tracing.Transport{Name: "test.Request", Next: http.DefaultTransport},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, testServer.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+
+	_ = resp.Body.Close()
+
+	entry := log.WithContext(ctx)
+	require.NoError(t, tracing.LogHook{}.Fire(entry))
+	require.Equal(t, span.SpanContext().TraceID().String(), entry.Data["trace_id"])
+
+	tracing.End(span, errors.New("test error")) //nolint:err113
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "test.Request", spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, spans[1].SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	require.Contains(t, traceparent, span.SpanContext().TraceID().String())
+
+	require.Equal(t, "test", spans[1].Name())
+	require.Equal(t, "test error", spans[1].Status().Description)
+}
+
+type errorTransport struct{}
+
+func (errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, &url.Error{Op: req.Method, URL: req.URL.String(), Err: errors.New("test error")} //nolint:err113
+}
+
+//nolint:paralleltest
+func TestTransportRedactsError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	client := &http.Client{
+		Transport: &tracing.Transport{Name: "test.Request", Next: errorTransport{}},
+	}
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, "https://hooks.slack.com/services/T000/B000/XXXX", nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	if resp != nil {
+		_ = resp.Body.Close()
+	}
+
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Contains(t, spans[0].Status().Description, "test error")
+	require.NotContains(t, spans[0].Status().Description, "XXXX")
+
+	for _, event := range spans[0].Events() {
+		for _, attribute := range event.Attributes {
+			require.NotContains(t, attribute.Value.Emit(), "XXXX")
+		}
+	}
+}
@@ -13,10 +13,14 @@ limitations under the License.
 package types
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type ScheduledEventsEventType string
@@ -24,35 +28,245 @@ type ScheduledEventsEventType string
 const (
 	// The Virtual Machine is scheduled to pause for a few seconds. CPU and network connectivity
 	// may be suspended, but there's no impact on memory or open files.
-	EventTypeFreeze = "Freeze"
+	EventTypeFreeze ScheduledEventsEventType = "Freeze"
 	// The Virtual Machine is scheduled for reboot (non-persistent memory is lost).
 	// This event is made available on a best effort basis.
-	EventTypeReboot = "Reboot"
+	EventTypeReboot ScheduledEventsEventType = "Reboot"
 	// The Virtual Machine is scheduled to move to another node (ephemeral disks are lost).
 	// This event is delivered on a best effort basis.
-	EventTypeRedeploy = "Redeploy"
+	EventTypeRedeploy ScheduledEventsEventType = "Redeploy"
 	// The Spot Virtual Machine is being deleted (ephemeral disks are lost).
-	EventTypePreempt = "Preempt"
+	EventTypePreempt ScheduledEventsEventType = "Preempt"
 	// The virtual machine is scheduled to be deleted.
-	EventTypeTerminate = "Terminate"
+	EventTypeTerminate ScheduledEventsEventType = "Terminate"
 )
 
+type EventStatus string
+
+const (
+	// The event is scheduled to start after NotBefore time.
+	EventStatusScheduled EventStatus = "Scheduled"
+	// The event has started, Virtual Machine is being affected by it.
+	EventStatusStarted EventStatus = "Started"
+	// The event has completed.
+	EventStatusCompleted EventStatus = "Completed"
+	// The event was canceled.
+	EventStatusCanceled EventStatus = "Canceled"
+)
+
+const (
+	// The event is initiated by Azure platform.
+	EventSourcePlatform = "Platform"
+	// The event is initiated by user, for example restart from Azure portal.
+	EventSourceUser = "User"
+	// The event is not from Azure, it was injected by handler to rehearse evictions.
+	EventSourceSimulated = "Simulated"
+)
+
+// DurationInSeconds is -1 if duration of the event is unknown.
+const unknownDurationInSeconds = -1
+
+// format of NotBefore field in scheduled events.
+const NotBeforeLayout = "Mon, 02 Jan 2006 15:04:05 GMT"
+
 // https://docs.microsoft.com/en-us/azure/virtual-machines/linux/scheduled-events
 type ScheduledEventsEvent struct {
 	EventId           string                   `description:"Globally unique identifier for this event."` //nolint:golint,revive,stylecheck
 	EventType         ScheduledEventsEventType `description:"Impact this event causes."`
 	ResourceType      string                   `description:"Type of resource this event affects."`
 	Resources         []string                 `description:"List of resources this event affects."`
-	EventStatus       string                   `description:"Status of this event."`
-	NotBefore         string                   `description:"Time after which this event can start. The event is guaranteed to not start before this time. Will be blank if the event has already started"` //nolint:lll
+	EventStatus       EventStatus              `description:"Status of this event."`
+	NotBefore         *time.Time               `description:"Time after which this event can start. The event is guaranteed to not start before this time. Will be blank if the event has already started"` //nolint:lll
 	Description       string                   `description:"Description of this event."`
 	EventSource       string                   `description:"Initiator of the event."`
 	DurationInSeconds int                      `description:"The expected duration of the interruption caused by the event."`
+	// fields that are not known by handler, new fields from Azure are passed to templates and hooks
+	Extra map[string]interface{} `json:"-" description:"Fields of event that are not modelled by handler"`
+}
+
+// names of fields that are modelled in ScheduledEventsEvent.
+var knownEventFields = []string{
+	"EventId", "EventType", "ResourceType", "Resources", "EventStatus",
+	"NotBefore", "Description", "EventSource", "DurationInSeconds",
 }
 
+// UnmarshalJSON parses NotBefore from format that is used in scheduled events,
+// NotBefore that is not recognized is left blank so other events in document are still decoded.
+func (e *ScheduledEventsEvent) UnmarshalJSON(data []byte) error {
+	type eventAlias ScheduledEventsEvent
+
+	event := struct {
+		*eventAlias
+		NotBefore string
+	}{
+		eventAlias: (*eventAlias)(e),
+	}
+
+	if err := json.Unmarshal(data, &event); err != nil {
+		return errors.Wrap(err, "error in json.Unmarshal")
+	}
+
+	extra, err := getExtraFields(data)
+	if err != nil {
+		return err
+	}
+
+	e.Extra = extra
+	e.NotBefore = nil
+
+	// NotBefore is blank if event has already started
+	if len(event.NotBefore) == 0 {
+		return nil
+	}
+
+	notBefore, err := parseNotBefore(event.NotBefore)
+	if err != nil {
+		log.WithError(err).Warnf("ignoring NotBefore of event %s", e.EventId)
+
+		return nil
+	}
+
+	e.NotBefore = &notBefore
+
+	return nil
+}
+
+// MarshalJSON formats NotBefore in format that is used in scheduled events.
+func (e ScheduledEventsEvent) MarshalJSON() ([]byte, error) {
+	type eventAlias ScheduledEventsEvent
+
+	result, err := json.Marshal(struct {
+		eventAlias
+		NotBefore string
+	}{
+		eventAlias: eventAlias(e),
+		NotBefore:  e.NotBeforeString(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error in json.Marshal")
+	}
+
+	if len(e.Extra) == 0 {
+		return result, nil
+	}
+
+	// pass through fields that are not modelled
+	fields := make(map[string]interface{})
+
+	if err := json.Unmarshal(result, &fields); err != nil {
+		return nil, errors.Wrap(err, "error in json.Unmarshal")
+	}
+
+	for key, value := range e.Extra {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+
+	result, err = json.Marshal(fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in json.Marshal")
+	}
+
+	return result, nil
+}
+
+// ExpectedDuration returns expected duration of the interruption,
+// returns zero if duration is unknown.
+func (e ScheduledEventsEvent) ExpectedDuration() time.Duration {
+	if e.DurationInSeconds <= unknownDurationInSeconds {
+		return 0
+	}
+
+	return time.Duration(e.DurationInSeconds) * time.Second
+}
+
+// returns fields of event that are not modelled, nil if all fields are known.
+func getExtraFields(data []byte) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, "error in json.Unmarshal")
+	}
+
+	for key := range fields {
+		for _, knownField := range knownEventFields {
+			// json fields are matched case insensitive
+			if strings.EqualFold(key, knownField) {
+				delete(fields, key)
+
+				break
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	return fields, nil
+}
+
+// NotBeforeString returns NotBefore in format that is used in scheduled events,
+// returns empty string if event has already started.
+func (e ScheduledEventsEvent) NotBeforeString() string {
+	if e.NotBefore == nil {
+		return ""
+	}
+
+	return e.NotBefore.UTC().Format(NotBeforeLayout)
+}
+
+// TimeUntilStart returns duration until event can start,
+// returns zero if event has already started.
+func (e ScheduledEventsEvent) TimeUntilStart() time.Duration {
+	if e.NotBefore == nil {
+		return 0
+	}
+
+	return max(time.Until(*e.NotBefore), 0)
+}
+
+// IsSimulated returns true if event was injected by handler and is not from Azure.
+func (e ScheduledEventsEvent) IsSimulated() bool {
+	return e.EventSource == EventSourceSimulated
+}
+
+// IsImminent returns true if event has already started or will start within threshold.
+func (e ScheduledEventsEvent) IsImminent(threshold time.Duration) bool {
+	return e.TimeUntilStart() <= threshold
+}
+
+func parseNotBefore(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC1123, time.RFC3339} {
+		if notBefore, err := time.Parse(layout, value); err == nil {
+			return notBefore, nil
+		}
+	}
+
+	return time.Time{}, errors.Errorf("NotBefore not recognized: %s", value)
+}
+
+type ProviderKind string
+
+const (
+	// Virtual Machine, kubelet also reports instances of Virtual Machine Scale Set
+	// with Flexible orchestration as Virtual Machines.
+	ProviderKindVirtualMachine ProviderKind = "VirtualMachine"
+	// Virtual Machine Scale Set with Uniform orchestration, instances have numeric IDs.
+	ProviderKindScaleSetUniform ProviderKind = "VirtualMachineScaleSetUniform"
+	// Azure Arc-enabled server.
+	ProviderKindHybridCompute ProviderKind = "HybridCompute"
+	// Azure Stack HCI (AKS hybrid) Virtual Machine.
+	ProviderKindMoc ProviderKind = "Moc"
+)
+
 var (
-	virtualMachineScaleSetsRe = regexp.MustCompile("^azure:///subscriptions/(.+)/resourceGroups/(.+)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines/(.+)$")
-	virtualMachineRe          = regexp.MustCompile("^azure:///subscriptions/(.+)/resourceGroups/(.+)/providers/Microsoft.Compute/virtualMachines/(.+)$")
+	azureProviderRe           = regexp.MustCompile("(?i)^azure:///?subscriptions/([^/]+)/resourceGroups/([^/]+)/providers/(.+)$")
+	virtualMachineScaleSetsRe = regexp.MustCompile("(?i)^Microsoft.Compute/virtualMachineScaleSets/([^/]+)/virtualMachines/([0-9]+)$")
+	virtualMachineRe          = regexp.MustCompile("(?i)^Microsoft.Compute/virtualMachines/([^/]+)$")
+	hybridComputeRe           = regexp.MustCompile("(?i)^Microsoft.HybridCompute/machines/([^/]+)$")
+	mocRe                     = regexp.MustCompile("(?i)^moc://([^/]+)$")
 )
 
 type AzureResource struct {
@@ -60,27 +274,62 @@ type AzureResource struct {
 	EventResourceName string
 	SubscriptionID    string
 	ResourceGroup     string
+	// kind of provider that was detected from providerID
+	Kind ProviderKind
+	// name of Virtual Machine Scale Set, empty for Virtual Machines
+	ScaleSetName string
+	// instance ID in Virtual Machine Scale Set with Uniform orchestration
+	InstanceID string
+	// name of Virtual Machine, empty for Virtual Machine Scale Set with Uniform orchestration
+	VMName string
 }
 
+// NewAzureResource parses providerID of Kubernetes node, providerID is parsed case insensitive.
 func NewAzureResource(providerID string) (*AzureResource, error) {
 	resource := &AzureResource{
 		ProviderID: providerID,
 	}
 
+	if v := mocRe.FindStringSubmatch(providerID); v != nil {
+		resource.Kind = ProviderKindMoc
+		resource.VMName = v[1]
+		resource.EventResourceName = v[1]
+
+		return resource, nil
+	}
+
+	v := azureProviderRe.FindStringSubmatch(providerID)
+	if v == nil {
+		return nil, errors.Errorf("providerID not recognized: %s", providerID)
+	}
+
+	resource.SubscriptionID = v[1]
+	resource.ResourceGroup = v[2]
+
+	provider := v[3]
+
 	switch {
-	case virtualMachineScaleSetsRe.MatchString(providerID):
-		v := virtualMachineScaleSetsRe.FindAllStringSubmatch(providerID, 1)
+	case virtualMachineScaleSetsRe.MatchString(provider):
+		v := virtualMachineScaleSetsRe.FindStringSubmatch(provider)
+
+		resource.Kind = ProviderKindScaleSetUniform
+		resource.ScaleSetName = v[1]
+		resource.InstanceID = v[2]
+		resource.EventResourceName = fmt.Sprintf("%s_%s", v[1], v[2])
+
+	case virtualMachineRe.MatchString(provider):
+		v := virtualMachineRe.FindStringSubmatch(provider)
 
-		resource.SubscriptionID = v[0][1]
-		resource.ResourceGroup = v[0][2]
-		resource.EventResourceName = fmt.Sprintf("%s_%s", v[0][3], v[0][4])
+		resource.Kind = ProviderKindVirtualMachine
+		resource.VMName = v[1]
+		resource.EventResourceName = v[1]
 
-	case virtualMachineRe.MatchString(providerID):
-		v := virtualMachineRe.FindAllStringSubmatch(providerID, 1)
+	case hybridComputeRe.MatchString(provider):
+		v := hybridComputeRe.FindStringSubmatch(provider)
 
-		resource.SubscriptionID = v[0][1]
-		resource.ResourceGroup = v[0][2]
-		resource.EventResourceName = v[0][3]
+		resource.Kind = ProviderKindHybridCompute
+		resource.VMName = v[1]
+		resource.EventResourceName = v[1]
 
 	default:
 		return nil, errors.Errorf("providerID not recognized: %s", providerID)
@@ -17,7 +17,9 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
 )
@@ -44,6 +46,195 @@ func TestScheduledEventsType(t *testing.T) {
 	if want := "VirtualMachine"; message.Events[0].ResourceType != want {
 		t.Fatalf("want=%s, got=%s", want, message.Events[0].ResourceType)
 	}
+
+	if want := types.EventStatusScheduled; message.Events[0].EventStatus != want {
+		t.Fatalf("want=%s, got=%s", want, message.Events[0].EventStatus)
+	}
+
+	if want := time.Date(2016, 9, 19, 18, 29, 47, 0, time.UTC); !message.Events[0].NotBefore.Equal(want) {
+		t.Fatalf("want=%s, got=%s", want, message.Events[0].NotBefore)
+	}
+
+	// NotBefore must be formatted as in scheduled events
+	eventBytes, err := json.Marshal(message.Events[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `"NotBefore":"Mon, 19 Sep 2016 18:29:47 GMT"`; !strings.Contains(string(eventBytes), want) {
+		t.Fatalf("want=%s, got=%s", want, string(eventBytes))
+	}
+}
+
+func TestScheduledEventsTypeAPIVersions(t *testing.T) {
+	t.Parallel()
+
+	type apiVersionTest struct {
+		file     string
+		want     []types.ScheduledEventsEvent
+		duration []time.Duration
+	}
+
+	tests := []apiVersionTest{
+		{
+			// EventSource and DurationInSeconds are not returned
+			file: "testdata/ScheduledEventsType-2019-01-01.json",
+			want: []types.ScheduledEventsEvent{
+				{EventType: types.EventTypeTerminate},
+			},
+			duration: []time.Duration{0},
+		},
+		{
+			file: "testdata/ScheduledEventsType-2020-07-01.json",
+			want: []types.ScheduledEventsEvent{
+				{EventType: types.EventTypePreempt, EventSource: types.EventSourcePlatform, DurationInSeconds: -1},
+				{EventType: types.EventTypeReboot, EventSource: types.EventSourceUser, DurationInSeconds: 120},
+			},
+			duration: []time.Duration{0, 2 * time.Minute},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			t.Parallel()
+
+			messageBytes, err := os.ReadFile(test.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			message := types.ScheduledEventsType{}
+
+			if err := json.Unmarshal(messageBytes, &message); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(message.Events) != len(test.want) {
+				t.Fatalf("want %d events, got=%d", len(test.want), len(message.Events))
+			}
+
+			for i, event := range message.Events {
+				want := test.want[i]
+
+				if event.EventType != want.EventType || event.EventSource != want.EventSource || event.DurationInSeconds != want.DurationInSeconds { //nolint:lll
+					t.Fatalf("want=%+v, got=%+v", want, event)
+				}
+
+				if event.ExpectedDuration() != test.duration[i] {
+					t.Fatalf("want=%s, got=%s", test.duration[i], event.ExpectedDuration())
+				}
+
+				if event.NotBefore == nil {
+					t.Fatal("NotBefore must be set")
+				}
+
+				if len(event.Extra) != 0 {
+					t.Fatalf("unexpected Extra=%+v", event.Extra)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduledEventsEventExtra(t *testing.T) {
+	t.Parallel()
+
+	event := types.ScheduledEventsEvent{}
+
+	if err := json.Unmarshal([]byte(`{"EventId":"1","eventType":"Reboot","DurationInSeconds":-1,"NewField":"value"}`), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.EventType != types.EventTypeReboot {
+		t.Fatalf("unexpected EventType=%s", event.EventType)
+	}
+
+	if len(event.Extra) != 1 || event.Extra["NewField"] != "value" {
+		t.Fatalf("unexpected Extra=%+v", event.Extra)
+	}
+
+	if event.ExpectedDuration() != 0 {
+		t.Fatal("unknown duration must be zero")
+	}
+
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `"NewField":"value"`; !strings.Contains(string(eventBytes), want) {
+		t.Fatalf("want=%s, got=%s", want, string(eventBytes))
+	}
+
+	event.DurationInSeconds = 10
+
+	if event.ExpectedDuration() != 10*time.Second {
+		t.Fatalf("unexpected ExpectedDuration=%s", event.ExpectedDuration())
+	}
+}
+
+func TestScheduledEventsEventNotBefore(t *testing.T) {
+	t.Parallel()
+
+	event := types.ScheduledEventsEvent{}
+
+	if err := json.Unmarshal([]byte(`{"EventId":"1","EventStatus":"Started","NotBefore":""}`), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.NotBefore != nil || event.TimeUntilStart() != 0 || !event.IsImminent(0) {
+		t.Fatal("started event must be imminent")
+	}
+
+	if err := json.Unmarshal([]byte(`{"EventId":"1","NotBefore":"invalid"}`), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.NotBefore != nil {
+		t.Fatalf("NotBefore must be blank, got=%s", event.NotBefore)
+	}
+
+	notBefore := time.Now().Add(10 * time.Minute)
+	event.NotBefore = &notBefore
+
+	if timeUntilStart := event.TimeUntilStart(); timeUntilStart <= 9*time.Minute || timeUntilStart > 10*time.Minute {
+		t.Fatalf("unexpected TimeUntilStart=%s", timeUntilStart)
+	}
+
+	if event.IsImminent(5 * time.Minute) {
+		t.Fatal("event must not be imminent")
+	}
+
+	if !event.IsImminent(15 * time.Minute) {
+		t.Fatal("event must be imminent")
+	}
+}
+
+func TestScheduledEventsTypeInvalidNotBefore(t *testing.T) {
+	t.Parallel()
+
+	message := types.ScheduledEventsType{}
+
+	document := `{"DocumentIncarnation":2,"Events":[
+		{"EventId":"1","EventType":"Reboot","NotBefore":"invalid"},
+		{"EventId":"2","EventType":"Freeze","NotBefore":"Mon, 19 Sep 2016 18:29:47 GMT"}
+	]}`
+
+	if err := json.Unmarshal([]byte(document), &message); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(message.Events) != 2 {
+		t.Fatalf("want 2 events, got=%d", len(message.Events))
+	}
+
+	if message.Events[0].EventType != types.EventTypeReboot || message.Events[0].NotBefore != nil {
+		t.Fatalf("unexpected event=%+v", message.Events[0])
+	}
+
+	if want := time.Date(2016, 9, 19, 18, 29, 47, 0, time.UTC); message.Events[1].NotBefore == nil || !message.Events[1].NotBefore.Equal(want) {
+		t.Fatalf("want=%s, got=%v", want, message.Events[1].NotBefore)
+	}
 }
 
 func TestAzureResource(t *testing.T) {
@@ -62,6 +253,9 @@ func TestAzureResource(t *testing.T) {
 			EventResourceName: "aks-spotcpu2v2-19654750-vmss_2768",
 			SubscriptionID:    "12345a05-1234-1234-12345-922b47912341",
 			ResourceGroup:     "mc_prod_prod_eastus",
+			Kind:              types.ProviderKindScaleSetUniform,
+			ScaleSetName:      "aks-spotcpu2v2-19654750-vmss",
+			InstanceID:        "2768",
 		},
 	})
 
@@ -71,6 +265,8 @@ func TestAzureResource(t *testing.T) {
 			EventResourceName: "test-openshift-cluste-t98dd-master-0",
 			SubscriptionID:    "12345a05-1234-1234-12345-922b47912342",
 			ResourceGroup:     "aro-infra-lth8qmzr-test-openshift-cluster1",
+			Kind:              types.ProviderKindVirtualMachine,
+			VMName:            "test-openshift-cluste-t98dd-master-0",
 		},
 	})
 
@@ -80,6 +276,55 @@ func TestAzureResource(t *testing.T) {
 			EventResourceName: "test-openshift-cluste-t98dd-worker-eastus1-rz2t8",
 			SubscriptionID:    "12345a05-1234-1234-12345-922b47912343",
 			ResourceGroup:     "aro-infra-lth8qmzr-test-openshift-cluster2",
+			Kind:              types.ProviderKindVirtualMachine,
+			VMName:            "test-openshift-cluste-t98dd-worker-eastus1-rz2t8",
+		},
+	})
+
+	// resource group in lower case
+	tests = append(tests, azureResourceTest{
+		providerID: "azure:///subscriptions/12345a05-1234-1234-12345-922b47912344/resourcegroups/mc_prod_prod_eastus/providers/Microsoft.Compute/virtualMachineScaleSets/aks-system-38511434-vmss/virtualMachines/12", //nolint:lll
+		want: &types.AzureResource{
+			EventResourceName: "aks-system-38511434-vmss_12",
+			SubscriptionID:    "12345a05-1234-1234-12345-922b47912344",
+			ResourceGroup:     "mc_prod_prod_eastus",
+			Kind:              types.ProviderKindScaleSetUniform,
+			ScaleSetName:      "aks-system-38511434-vmss",
+			InstanceID:        "12",
+		},
+	})
+
+	// Virtual Machine Scale Set with Flexible orchestration, kubelet reports instances as Virtual Machines
+	tests = append(tests, azureResourceTest{
+		providerID: "azure:///subscriptions/12345a05-1234-1234-12345-922b47912345/resourceGroups/MC_prod_prod_eastus/providers/Microsoft.Compute/virtualMachines/aks-flex-27458463-vms1", //nolint:lll
+		want: &types.AzureResource{
+			EventResourceName: "aks-flex-27458463-vms1",
+			SubscriptionID:    "12345a05-1234-1234-12345-922b47912345",
+			ResourceGroup:     "MC_prod_prod_eastus",
+			Kind:              types.ProviderKindVirtualMachine,
+			VMName:            "aks-flex-27458463-vms1",
+		},
+	})
+
+	// Azure Arc-enabled server
+	tests = append(tests, azureResourceTest{
+		providerID: "azure:///subscriptions/12345a05-1234-1234-12345-922b47912346/resourceGroups/arc-servers/providers/Microsoft.HybridCompute/machines/onprem-node-1", //nolint:lll
+		want: &types.AzureResource{
+			EventResourceName: "onprem-node-1",
+			SubscriptionID:    "12345a05-1234-1234-12345-922b47912346",
+			ResourceGroup:     "arc-servers",
+			Kind:              types.ProviderKindHybridCompute,
+			VMName:            "onprem-node-1",
+		},
+	})
+
+	// Azure Stack HCI
+	tests = append(tests, azureResourceTest{
+		providerID: "moc://moc-lm8trfqzsa3",
+		want: &types.AzureResource{
+			EventResourceName: "moc-lm8trfqzsa3",
+			Kind:              types.ProviderKindMoc,
+			VMName:            "moc-lm8trfqzsa3",
 		},
 	})
 
@@ -105,4 +350,13 @@ func TestAzureResource(t *testing.T) {
 	if _, err := types.NewAzureResource("azure://fake"); err == nil {
 		t.Fatal("error expected")
 	}
+
+	if _, err := types.NewAzureResource("azure:///subscriptions/1/resourceGroups/2/providers/Microsoft.Network/loadBalancers/3"); err == nil {
+		t.Fatal("error expected")
+	}
+
+	// instances of Virtual Machine Scale Set with Uniform orchestration have numeric IDs
+	if _, err := types.NewAzureResource("azure:///subscriptions/1/resourceGroups/2/providers/Microsoft.Compute/virtualMachineScaleSets/3/virtualMachines/vm"); err == nil { //nolint:lll
+		t.Fatal("error expected")
+	}
 }
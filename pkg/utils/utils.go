@@ -14,11 +14,16 @@ package utils
 
 import (
 	"context"
+	"net/url"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Redacted is a value of hidden secret.
+const Redacted = "<redacted>"
+
 func SleepWithContext(ctx context.Context, d time.Duration) {
 	log.Debugf("Sleep %s", d)
 
@@ -29,3 +34,58 @@ func SleepWithContext(ctx context.Context, d time.Duration) {
 		return
 	}
 }
+
+// RedactURL returns scheme and host of URL, credentials, path and query are hidden because they can contain tokens.
+func RedactURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || len(parsedURL.Host) == 0 {
+		return Redacted
+	}
+
+	result := url.URL{Scheme: parsedURL.Scheme, Host: parsedURL.Host}
+
+	if parsedURL.User != nil || len(strings.Trim(parsedURL.Path, "/")) > 0 || len(parsedURL.RawQuery) > 0 {
+		return result.String() + "/" + Redacted
+	}
+
+	return result.String()
+}
+
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactURLError returns error with redacted URL of request, URL of *url.Error is redacted in copy of it,
+// errors.Is and errors.As are working with returned error.
+func RedactURLError(err error, rawURL string) error {
+	if err == nil || len(rawURL) == 0 {
+		return err
+	}
+
+	if urlError, ok := err.(*url.Error); ok { //nolint:errorlint
+		return &url.Error{
+			Op:  urlError.Op,
+			URL: RedactURL(urlError.URL),
+			Err: RedactURLError(urlError.Err, rawURL),
+		}
+	}
+
+	// errors of HTTP clients can contain URL in message
+	if !strings.Contains(err.Error(), rawURL) {
+		return err
+	}
+
+	return &redactedError{
+		message: strings.ReplaceAll(err.Error(), rawURL, RedactURL(rawURL)),
+		err:     err,
+	}
+}
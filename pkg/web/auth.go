@@ -0,0 +1,147 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/client"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// caller must be allowed to use this verb on node to drain it, for example
+// rules: [{apiGroups: [""], resources: ["nodes"], verbs: ["drain"]}].
+const drainVerb = "drain"
+
+const authRealm = "aks-node-termination-handler"
+
+var (
+	errNoBearerToken    = errors.New("bearer token is required")
+	errInvalidToken     = errors.New("invalid bearer token")
+	errNotAuthorized    = errors.New("caller is not allowed to drain node")
+	errUnknownDrainAuth = errors.New("unknown authentication")
+	errNoKubernetesAuth = errors.New("kubernetes authentication is not available in standalone mode")
+)
+
+// authenticateDrain returns identity of caller that is allowed to drain node.
+func authenticateDrain(r *http.Request) (string, error) {
+	switch *config.Get().DrainAuth {
+	case config.DrainAuthNone:
+		return "anonymous", nil
+	case config.DrainAuthToken:
+		token, ok := getBearerToken(r)
+		if !ok {
+			return "", errNoBearerToken
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(*config.Get().DrainToken)) != 1 {
+			return "", errInvalidToken
+		}
+
+		return "token", nil
+	case config.DrainAuthKubernetes:
+		if config.Get().IsStandalone() {
+			return "", errNoKubernetesAuth
+		}
+
+		token, ok := getBearerToken(r)
+		if !ok {
+			return "", errNoBearerToken
+		}
+
+		return authorizeKubernetesToken(r.Context(), token)
+	default:
+		return "", errUnknownDrainAuth
+	}
+}
+
+// checks token with TokenReview and permissions of token user with SubjectAccessReview.
+func authorizeKubernetesToken(ctx context.Context, token string) (string, error) {
+	tokenReview, err := client.GetKubernetesClient().AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "error in tokenreviews.create")
+	}
+
+	if !tokenReview.Status.Authenticated {
+		return "", errors.Wrap(errInvalidToken, tokenReview.Status.Error)
+	}
+
+	user := tokenReview.Status.User
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	accessReview, err := client.GetKubernetesClient().AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:     drainVerb,
+				Resource: "nodes",
+				Name:     *config.Get().NodeName,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "error in subjectaccessreviews.create")
+	}
+
+	if !accessReview.Status.Allowed {
+		return "", errors.Wrap(errNotAuthorized, user.Username)
+	}
+
+	return user.Username, nil
+}
+
+// missing or invalid token is 401 with challenge, caller without permissions is 403,
+// invalid configuration is 500 and failed TokenReview or SubjectAccessReview requests are 503.
+func writeAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errNoBearerToken):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+authRealm+`"`)
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, errInvalidToken):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+authRealm+`", error="invalid_token"`)
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, errNotAuthorized):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, errUnknownDrainAuth), errors.Is(err, errNoKubernetesAuth):
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+	}
+}
+
+func getBearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || len(strings.TrimSpace(token)) == 0 {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,282 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/api"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/status"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const maxRequestBody = 1 << 20
+
+// node is tainted, annotated and cordoned before drain, eviction of pods is bounded by NodeGracePeriod.
+const manualDrainOverhead = 30 * time.Second
+
+var errUnknownEventType = errors.New("unknown eventType")
+
+var allowedDrainEventTypes = []types.ScheduledEventsEventType{
+	types.EventTypeFreeze,
+	types.EventTypeReboot,
+	types.EventTypeRedeploy,
+	types.EventTypePreempt,
+	types.EventTypeTerminate,
+}
+
+var (
+	notifier      func(ctx context.Context, event types.ScheduledEventsEvent) error
+	notifierMutex sync.RWMutex
+)
+
+// SetNotifier sets function that sends notifications about manual drains.
+func SetNotifier(notify func(ctx context.Context, event types.ScheduledEventsEvent) error) {
+	notifierMutex.Lock()
+	defer notifierMutex.Unlock()
+
+	notifier = notify
+}
+
+func getNotifier() func(ctx context.Context, event types.ScheduledEventsEvent) error {
+	notifierMutex.RLock()
+	defer notifierMutex.RUnlock()
+
+	return notifier
+}
+
+type drainRequest struct {
+	// type of event that is used in taints and notifications, default is Preempt
+	EventType types.ScheduledEventsEventType `json:"eventType"`
+	// only list pods that will be evicted
+	DryRun bool `json:"dryRun"`
+	// do not send notifications
+	SkipNotifications bool `json:"skipNotifications"`
+}
+
+type drainResponse struct {
+	Node        string                         `json:"node"`
+	EventID     string                         `json:"eventId,omitempty"`
+	EventType   types.ScheduledEventsEventType `json:"eventType"`
+	DryRun      bool                           `json:"dryRun"`
+	Caller      string                         `json:"caller,omitempty"`
+	Outcome     status.Outcome                 `json:"outcome,omitempty"`
+	EvictedPods []string                       `json:"evictedPods"`
+	Failures    []string                       `json:"failures"`
+	Error       string                         `json:"error,omitempty"`
+}
+
+// POST drains node, GET with eventId returns result of drain that was started before.
+func handlerDrainNode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "only GET and POST methods are allowed")
+
+		return
+	}
+
+	if config.Get().IsStandalone() {
+		writeJSONError(w, http.StatusNotImplemented, "node can not be drained in standalone mode")
+
+		return
+	}
+
+	caller, err := authenticateDrain(r)
+	if err != nil {
+		log.WithError(err).Warn("drain request is not authorized")
+		writeAuthError(w, err)
+
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		response, ok := getDrainResult(r.URL.Query().Get("eventId"))
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "drain not found")
+
+			return
+		}
+
+		writeJSON(w, response)
+
+		return
+	}
+
+	request, err := parseDrainRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	response := drainNode(r.Context(), caller, request)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case len(response.Error) > 0:
+		w.WriteHeader(http.StatusInternalServerError)
+	case !request.DryRun:
+		// drain takes longer than request timeout, result is available with GET by eventId
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func parseDrainRequest(r *http.Request) (*drainRequest, error) {
+	request := &drainRequest{}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading body")
+	}
+
+	// empty body uses defaults
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, request); err != nil {
+			return nil, errors.Wrap(err, "invalid body")
+		}
+	}
+
+	if len(request.EventType) == 0 {
+		request.EventType = types.EventTypePreempt
+	}
+
+	if !slices.Contains(allowedDrainEventTypes, request.EventType) {
+		return nil, errors.Wrap(errUnknownEventType, string(request.EventType))
+	}
+
+	return request, nil
+}
+
+func drainNode(ctx context.Context, caller string, request *drainRequest) *drainResponse {
+	nodeName := *config.Get().NodeName
+
+	log.Warnf("Manual drain of node %s requested by %s: %+v", nodeName, caller, request)
+
+	response := &drainResponse{
+		Node:        nodeName,
+		EventType:   request.EventType,
+		DryRun:      request.DryRun,
+		Caller:      caller,
+		EvictedPods: make([]string, 0),
+		Failures:    make([]string, 0),
+	}
+
+	eventMessage := fmt.Sprintf(config.EventMessageManualDrain, caller, request.EventType, request.DryRun)
+	if err := api.AddNodeEvent(ctx, "Warning", "ManualDrain", eventMessage); err != nil {
+		log.WithError(err).Error("error in add node event")
+	}
+
+	if request.DryRun {
+		pods, err := api.PodsForDeletion(ctx, nodeName)
+		if err != nil {
+			response.Error = err.Error()
+
+			return response
+		}
+
+		response.EvictedPods = pods
+
+		return response
+	}
+
+	event := types.ScheduledEventsEvent{
+		EventId:     fmt.Sprintf("manual-%d", time.Now().Unix()),
+		EventType:   request.EventType,
+		EventStatus: types.EventStatusStarted,
+		EventSource: types.EventSourceUser,
+		Description: eventMessage,
+	}
+
+	if notify := getNotifier(); notify != nil && !request.SkipNotifications {
+		// send event in separate goroutine, request can be canceled before notification is sent
+		go func() {
+			if err := notify(context.WithoutCancel(ctx), event); err != nil {
+				log.WithError(err).Error("error in notify")
+			}
+		}()
+	}
+
+	status.RecordEvent(event)
+	status.SetEventOutcome(event.EventId, status.OutcomeDraining, nil)
+
+	response.EventID = event.EventId
+	response.Outcome = status.OutcomeDraining
+
+	// drain is not canceled when request is finished, it's bounded by drain timeout
+	go func() {
+		drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), config.Get().NodeGracePeriod()+manualDrainOverhead)
+		defer cancel()
+
+		result, err := api.DrainNode(drainCtx, nodeName, string(event.EventType), event.EventId)
+		if result != nil {
+			status.SetEventPods(event.EventId, result.EvictedPods, result.Failures)
+		}
+
+		if err != nil {
+			log.WithError(err).Errorf("error in manual drain of node %s", nodeName)
+
+			status.SetEventOutcome(event.EventId, status.OutcomeFailed, err)
+
+			return
+		}
+
+		log.Infof("Manual drain of node %s finished, evicted pods %v, failures %v", nodeName, result.EvictedPods, result.Failures)
+
+		status.SetEventOutcome(event.EventId, status.OutcomeDrained, nil)
+	}()
+
+	return response
+}
+
+// getDrainResult returns result of manual drain from status of event.
+func getDrainResult(eventID string) (*drainResponse, bool) {
+	record, ok := status.GetEvent(eventID)
+	if !ok || record.Event.EventSource != types.EventSourceUser {
+		return nil, false
+	}
+
+	response := &drainResponse{
+		Node:        *config.Get().NodeName,
+		EventID:     record.Event.EventId,
+		EventType:   record.Event.EventType,
+		Outcome:     record.Outcome,
+		EvictedPods: make([]string, 0),
+		Failures:    make([]string, 0),
+		Error:       record.Error,
+	}
+
+	response.EvictedPods = append(response.EvictedPods, record.EvictedPods...)
+	response.Failures = append(response.Failures, record.Failures...)
+
+	return response, true
+}
+
+func writeJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,242 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/client"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/status"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/web"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+//nolint:paralleltest
+func TestDrainNodeRequest(t *testing.T) {
+	drainAuth := config.DrainAuthToken
+	drainToken := "test-token"
+	nodeName := "test-node"
+	standalone := false
+
+	config.Set(config.Type{
+		DrainAuth:  &drainAuth,
+		DrainToken: &drainToken,
+		NodeName:   &nodeName,
+		Standalone: &standalone,
+	})
+
+	testCases := []struct {
+		name       string
+		method     string
+		token      string
+		body       string
+		statusCode int
+	}{
+		{name: "method", method: http.MethodPut, token: drainToken, statusCode: http.StatusMethodNotAllowed},
+		{name: "unknownDrain", method: http.MethodGet, token: drainToken, statusCode: http.StatusNotFound},
+		{name: "noToken", method: http.MethodPost, statusCode: http.StatusUnauthorized},
+		{name: "invalidToken", method: http.MethodPost, token: "invalid", statusCode: http.StatusUnauthorized},
+		{name: "invalidBody", method: http.MethodPost, token: drainToken, body: "{", statusCode: http.StatusBadRequest},
+		{name: "invalidEventType", method: http.MethodPost, token: drainToken, body: `{"eventType":"Fake"}`, statusCode: http.StatusBadRequest},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(testCase.method, "/drainNode", strings.NewReader(testCase.body))
+			if len(testCase.token) > 0 {
+				req.Header.Set("Authorization", "Bearer "+testCase.token)
+			}
+
+			w := httptest.NewRecorder()
+
+			web.GetHandler().ServeHTTP(w, req)
+
+			require.Equal(t, testCase.statusCode, w.Code, w.Body.String())
+			require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			if testCase.statusCode == http.StatusUnauthorized {
+				require.True(t, strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer "))
+			}
+		})
+	}
+}
+
+//nolint:paralleltest
+func TestDrainNodeAccepted(t *testing.T) {
+	drainAuth := config.DrainAuthToken
+	drainToken := "test-token"
+	nodeName := "test-node"
+	standalone := false
+	dryRun := false
+	nodeGracePeriodSeconds := 10
+	eventsNamespace := "default"
+
+	config.Set(config.Type{
+		DrainAuth:              &drainAuth,
+		DrainToken:             &drainToken,
+		NodeName:               &nodeName,
+		Standalone:             &standalone,
+		DryRun:                 &dryRun,
+		NodeGracePeriodSeconds: &nodeGracePeriodSeconds,
+		EventsNamespace:        &eventsNamespace,
+	})
+
+	// node that is already cordoned is not drained again
+	client.SetKubernetesClient(fake.NewClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/drainNode", strings.NewReader(`{"skipNotifications":true}`))
+	req.Header.Set("Authorization", "Bearer "+drainToken)
+
+	w := httptest.NewRecorder()
+
+	web.GetHandler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	response := struct {
+		EventID string `json:"eventId"`
+	}{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotEmpty(t, response.EventID)
+
+	// drain is finished in background, outcome is available in status
+	require.Eventually(t, func() bool {
+		for _, record := range status.Events() {
+			if record.Event.EventId == response.EventID {
+				return record.Outcome == status.OutcomeDrained
+			}
+		}
+
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// result of drain is returned by eventId
+	req = httptest.NewRequest(http.MethodGet, "/drainNode?eventId="+response.EventID, nil)
+	req.Header.Set("Authorization", "Bearer "+drainToken)
+
+	w = httptest.NewRecorder()
+
+	web.GetHandler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	result := struct {
+		EventID     string         `json:"eventId"`
+		Outcome     status.Outcome `json:"outcome"`
+		EvictedPods []string       `json:"evictedPods"`
+		Failures    []string       `json:"failures"`
+	}{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Equal(t, response.EventID, result.EventID)
+	require.Equal(t, status.OutcomeDrained, result.Outcome)
+	require.NotNil(t, result.EvictedPods)
+	require.NotNil(t, result.Failures)
+
+	// pods and failures of drain are listed in result
+	status.SetEventPods(response.EventID, []string{"default/pod1"}, []string{"default/pod2: test error"})
+
+	w = httptest.NewRecorder()
+
+	web.GetHandler().ServeHTTP(w, req)
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Equal(t, []string{"default/pod1"}, result.EvictedPods)
+	require.Equal(t, []string{"default/pod2: test error"}, result.Failures)
+}
+
+//nolint:paralleltest,funlen
+func TestDrainNodeKubernetesAuth(t *testing.T) {
+	drainAuth := config.DrainAuthKubernetes
+	nodeName := "test-node"
+	standalone := false
+	eventsNamespace := "default"
+	podGracePeriodSeconds := -1
+	nodeGracePeriodSeconds := 10
+	disableEviction := false
+
+	config.Set(config.Type{
+		DrainAuth:              &drainAuth,
+		NodeName:               &nodeName,
+		Standalone:             &standalone,
+		EventsNamespace:        &eventsNamespace,
+		PodGracePeriodSeconds:  &podGracePeriodSeconds,
+		NodeGracePeriodSeconds: &nodeGracePeriodSeconds,
+		DisableEviction:        &disableEviction,
+	})
+	defer client.SetKubernetesClient(nil)
+
+	testCases := []struct {
+		name        string
+		tokenReview error
+		allowed     bool
+		accessError error
+		statusCode  int
+	}{
+		{name: "allowed", allowed: true, statusCode: http.StatusOK},
+		{name: "notAllowed", statusCode: http.StatusForbidden},
+		{name: "tokenReviewError", tokenReview: apierrors.NewServiceUnavailable("test"), statusCode: http.StatusServiceUnavailable},
+		{name: "accessReviewError", accessError: apierrors.NewServiceUnavailable("test"), statusCode: http.StatusServiceUnavailable},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			clientset := fake.NewClientset(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			})
+
+			clientset.PrependReactor("create", "tokenreviews", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+				return true, &authenticationv1.TokenReview{
+					Status: authenticationv1.TokenReviewStatus{
+						Authenticated: true,
+						User:          authenticationv1.UserInfo{Username: "test-user"},
+					},
+				}, testCase.tokenReview
+			})
+
+			clientset.PrependReactor("create", "subjectaccessreviews", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+				return true, &authorizationv1.SubjectAccessReview{
+					Status: authorizationv1.SubjectAccessReviewStatus{Allowed: testCase.allowed},
+				}, testCase.accessError
+			})
+
+			client.SetKubernetesClient(clientset)
+
+			req := httptest.NewRequest(http.MethodPost, "/drainNode", strings.NewReader(`{"dryRun":true}`))
+			req.Header.Set("Authorization", "Bearer test-token")
+
+			w := httptest.NewRecorder()
+
+			web.GetHandler().ServeHTTP(w, req)
+
+			require.Equal(t, testCase.statusCode, w.Code, w.Body.String())
+			require.Empty(t, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/alert"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/api"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	checkStatusOK    = "ok"
+	checkStatusError = "error"
+)
+
+var (
+	errReaderNotStarted    = errors.New("reader is not started")
+	errNoSuccessfulRead    = errors.New("events endpoint was not read successfully yet")
+	errEndpointUnavailable = errors.New("events endpoint is not available")
+	errReaderStuck         = errors.New("reading events is not progressing")
+)
+
+var (
+	reader      *events.Reader
+	readerMutex sync.RWMutex
+)
+
+// SetReader sets events reader that is used in health checks.
+func SetReader(eventReader *events.Reader) {
+	readerMutex.Lock()
+	defer readerMutex.Unlock()
+
+	reader = eventReader
+}
+
+func getReader() *events.Reader {
+	readerMutex.RLock()
+	defer readerMutex.RUnlock()
+
+	return reader
+}
+
+type checkResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type healthResult struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+type healthCheck func(ctx context.Context) (string, error)
+
+func handlerReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]healthCheck{
+		"imds":     checkEventsEndpoint,
+		"notifier": checkNotifier,
+	}
+
+	// there is no Kubernetes in standalone mode
+	if !config.Get().IsStandalone() {
+		checks["kubernetes"] = checkKubernetes
+	}
+
+	writeHealthResult(w, r, checks)
+}
+
+func handlerLivez(w http.ResponseWriter, r *http.Request) {
+	writeHealthResult(w, r, map[string]healthCheck{
+		"reader": checkReaderProgress,
+	})
+}
+
+func writeHealthResult(w http.ResponseWriter, r *http.Request, checks map[string]healthCheck) {
+	result := healthResult{
+		Status: checkStatusOK,
+		Checks: make(map[string]checkResult, len(checks)),
+	}
+
+	for name, check := range checks {
+		message, err := check(r.Context())
+		if err != nil {
+			log.WithError(err).Errorf("health check %s failed", name)
+
+			result.Status = checkStatusError
+			result.Checks[name] = checkResult{Status: checkStatusError, Message: err.Error()}
+
+			continue
+		}
+
+		result.Checks[name] = checkResult{Status: checkStatusOK, Message: message}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if result.Status != checkStatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// check that events endpoint was read successfully recently.
+func checkEventsEndpoint(_ context.Context) (string, error) {
+	eventReader := getReader()
+	if eventReader == nil {
+		return "", errReaderNotStarted
+	}
+
+	lastSuccess := eventReader.LastSuccess()
+	if lastSuccess.IsZero() {
+		return "", errNoSuccessfulRead
+	}
+
+	if failures := eventReader.ConsecutiveFailures(); eventReader.FailureThreshold > 0 && failures >= eventReader.FailureThreshold {
+		return "", errors.Wrapf(errEndpointUnavailable, "%d consecutive failures", failures)
+	}
+
+	age := time.Since(lastSuccess)
+	if age > eventReader.MaxInterval() {
+		return "", errors.Wrapf(errEndpointUnavailable, "last successful read %s ago", age.Round(time.Second))
+	}
+
+	return fmt.Sprintf("last successful read %s ago", age.Round(time.Second)), nil
+}
+
+// handler reads node directly from Kubernetes API and does not use informers,
+// so there is no informer cache that needs to be synced, reading node is enough.
+func checkKubernetes(ctx context.Context) (string, error) {
+	if _, err := api.GetNode(ctx, *config.Get().NodeName); err != nil {
+		return "", errors.Wrap(err, "kubernetes API is not available")
+	}
+
+	return "", nil
+}
+
+func checkNotifier(_ context.Context) (string, error) {
+	if err := alert.Ping(); err != nil {
+		return "", errors.Wrap(err, "alerts transport is not working")
+	}
+
+	return "", nil
+}
+
+// check that reading loop is not stuck.
+func checkReaderProgress(_ context.Context) (string, error) {
+	eventReader := getReader()
+	if eventReader == nil {
+		return "reader is not started", nil
+	}
+
+	if !eventReader.IsProgressing() {
+		return "", errReaderStuck
+	}
+
+	return "", nil
+}
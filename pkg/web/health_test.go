@@ -0,0 +1,164 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/client"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/web"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Checks map[string]struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	} `json:"checks"`
+}
+
+func getHealth(t *testing.T, path string) (int, healthResponse) {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	web.GetHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+	response := healthResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response), path)
+
+	return rr.Code, response
+}
+
+//nolint:paralleltest,funlen
+func TestHealth(t *testing.T) {
+	nodeName := "health-node"
+	standalone := false
+	telegramToken := ""
+
+	config.Set(config.Type{
+		NodeName:      &nodeName,
+		Standalone:    &standalone,
+		TelegramToken: &telegramToken,
+	})
+
+	client.SetKubernetesClient(fake.NewClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+	}))
+	defer client.SetKubernetesClient(nil)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"DocumentIncarnation":1,"Events":[]}`))
+	}))
+	defer testServer.Close()
+
+	defer web.SetReader(nil)
+
+	t.Run("not ready", func(t *testing.T) {
+		web.SetReader(nil)
+
+		code, response := getHealth(t, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, "error", response.Status)
+		require.Equal(t, "error", response.Checks["imds"].Status)
+		require.Equal(t, "ok", response.Checks["kubernetes"].Status)
+		require.Equal(t, "ok", response.Checks["notifier"].Status)
+
+		// reader that is not started is alive
+		code, response = getHealth(t, "/livez")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "ok", response.Status)
+
+		// events endpoint was not read yet
+		web.SetReader(events.NewReader())
+
+		code, response = getHealth(t, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Contains(t, response.Checks["imds"].Message, "not read successfully")
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		eventReader := events.NewReader()
+		eventReader.Endpoint = testServer.URL
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		go eventReader.ReadEvents(ctx)
+
+		web.SetReader(eventReader)
+
+		require.Eventually(t, func() bool {
+			return !eventReader.LastSuccess().IsZero()
+		}, 5*time.Second, 10*time.Millisecond)
+
+		code, response := getHealth(t, "/readyz")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "ok", response.Status)
+		require.Len(t, response.Checks, 3)
+
+		code, response = getHealth(t, "/livez")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "ok", response.Checks["reader"].Status)
+	})
+
+	t.Run("kubernetes not available", func(t *testing.T) {
+		client.SetKubernetesClient(fake.NewClientset())
+
+		code, response := getHealth(t, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, "error", response.Checks["kubernetes"].Status)
+	})
+
+	t.Run("stale reader", func(t *testing.T) {
+		client.SetKubernetesClient(fake.NewClientset(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		}))
+
+		eventReader := events.NewReader()
+		eventReader.Endpoint = testServer.URL
+		eventReader.Period = time.Millisecond
+		eventReader.ScheduledPeriod = time.Millisecond
+		eventReader.MaxBackoff = time.Millisecond
+		eventReader.RequestTimeout = 10 * time.Millisecond
+
+		// reading loop reads endpoint and stops without next reads
+		ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+		defer cancel()
+
+		eventReader.ReadEvents(ctx)
+
+		web.SetReader(eventReader)
+
+		require.False(t, eventReader.LastSuccess().IsZero())
+		time.Sleep(2 * eventReader.MaxInterval())
+
+		code, response := getHealth(t, "/livez")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, "error", response.Checks["reader"].Status)
+
+		code, response = getHealth(t, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Contains(t, response.Checks["imds"].Message, "last successful read")
+	})
+}
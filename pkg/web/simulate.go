@@ -0,0 +1,170 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var errUnknownEventStatus = errors.New("eventStatus must be Scheduled or Started")
+
+// SimulateRequest is a body of /simulate request.
+type SimulateRequest struct {
+	// type of simulated event, default is Preempt
+	EventType types.ScheduledEventsEventType `json:"eventType"`
+	// status of simulated event, default is Scheduled
+	EventStatus types.EventStatus `json:"eventStatus"`
+	// offset of NotBefore from now, for example 5m, ignored for started events
+	NotBefore string `json:"notBefore"`
+	// simulate that event is gone from document after it was processed
+	ClearEvent bool `json:"clearEvent"`
+}
+
+type simulateResponse struct {
+	Caller string                     `json:"caller"`
+	Event  types.ScheduledEventsEvent `json:"event"`
+}
+
+func handlerSimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "only POST method is allowed")
+
+		return
+	}
+
+	// simulated event drains node, caller must be allowed to drain node
+	caller, err := authenticateDrain(r)
+	if err != nil {
+		log.WithError(err).Warn("simulate request is not authorized")
+		writeAuthError(w, err)
+
+		return
+	}
+
+	eventReader := getReader()
+	if eventReader == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, errReaderNotStarted.Error())
+
+		return
+	}
+
+	request, err := parseSimulateRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	event, err := request.newEvent(caller, eventReader.AzureResource)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	// event is processed by reading loop, processing includes drain and takes longer than request timeout,
+	// progress is available in /status
+	if err := eventReader.SimulateEvent(event, request.ClearEvent); err != nil {
+		writeJSONError(w, http.StatusConflict, err.Error())
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	_ = json.NewEncoder(w).Encode(simulateResponse{
+		Caller: caller,
+		Event:  event,
+	})
+}
+
+func parseSimulateRequest(r *http.Request) (*SimulateRequest, error) {
+	request := &SimulateRequest{}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading body")
+	}
+
+	// empty body uses defaults
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, request); err != nil {
+			return nil, errors.Wrap(err, "invalid body")
+		}
+	}
+
+	if len(request.EventType) == 0 {
+		request.EventType = types.EventTypePreempt
+	}
+
+	if len(request.EventStatus) == 0 {
+		request.EventStatus = types.EventStatusScheduled
+	}
+
+	if !slices.Contains(allowedDrainEventTypes, request.EventType) {
+		return nil, errors.Wrap(errUnknownEventType, string(request.EventType))
+	}
+
+	if request.EventStatus != types.EventStatusScheduled && request.EventStatus != types.EventStatusStarted {
+		return nil, errors.Wrap(errUnknownEventStatus, string(request.EventStatus))
+	}
+
+	return request, nil
+}
+
+// returns synthetic event in the same form as events from scheduled events endpoint.
+func (request *SimulateRequest) newEvent(caller, azureResource string) (types.ScheduledEventsEvent, error) {
+	event := types.ScheduledEventsEvent{
+		EventId:           fmt.Sprintf("simulated-%d", time.Now().UnixNano()),
+		EventType:         request.EventType,
+		ResourceType:      "VirtualMachine",
+		Resources:         []string{azureResource},
+		EventStatus:       request.EventStatus,
+		Description:       "Simulated event requested by " + caller,
+		EventSource:       types.EventSourceSimulated,
+		DurationInSeconds: -1,
+	}
+
+	// NotBefore is blank if event has already started
+	if request.EventStatus == types.EventStatusStarted {
+		return event, nil
+	}
+
+	notBefore := time.Now()
+
+	if len(request.NotBefore) > 0 {
+		offset, err := time.ParseDuration(request.NotBefore)
+		if err != nil {
+			return event, errors.Wrap(err, "invalid notBefore")
+		}
+
+		notBefore = notBefore.Add(offset)
+	}
+
+	// scheduled events have seconds precision
+	notBefore = notBefore.Truncate(time.Second)
+	event.NotBefore = &notBefore
+
+	return event, nil
+}
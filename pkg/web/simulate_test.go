@@ -0,0 +1,119 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/web"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest,funlen
+func TestSimulate(t *testing.T) {
+	drainAuth := config.DrainAuthToken
+	drainToken := "test-token"
+	nodeName := "test-node"
+	standalone := false
+
+	config.Set(config.Type{
+		DrainAuth:  &drainAuth,
+		DrainToken: &drainToken,
+		NodeName:   &nodeName,
+		Standalone: &standalone,
+	})
+
+	receivedEvents := make(chan types.ScheduledEventsEvent, 1)
+
+	eventReader := events.NewReader()
+	eventReader.AzureResource = "test-resource"
+	eventReader.EventReceived = func(_ context.Context, event types.ScheduledEventsEvent) (bool, error) {
+		receivedEvents <- event
+
+		return false, nil
+	}
+
+	// simulated events are processed by reading loop
+	testServer := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	defer testServer.Close()
+
+	eventReader.Endpoint = testServer.URL
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	go eventReader.ReadEvents(ctx)
+
+	web.SetReader(eventReader)
+	defer web.SetReader(nil)
+
+	testCases := []struct {
+		name       string
+		method     string
+		token      string
+		body       string
+		statusCode int
+	}{
+		{name: "method", method: http.MethodGet, token: drainToken, statusCode: http.StatusMethodNotAllowed},
+		{name: "noToken", method: http.MethodPost, statusCode: http.StatusUnauthorized},
+		{name: "invalidEventType", method: http.MethodPost, token: drainToken, body: `{"eventType":"Fake"}`, statusCode: http.StatusBadRequest},
+		{name: "invalidEventStatus", method: http.MethodPost, token: drainToken, body: `{"eventStatus":"Completed"}`, statusCode: http.StatusBadRequest},
+		{name: "invalidNotBefore", method: http.MethodPost, token: drainToken, body: `{"notBefore":"fake"}`, statusCode: http.StatusBadRequest},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(testCase.method, "/simulate", strings.NewReader(testCase.body))
+			if len(testCase.token) > 0 {
+				req.Header.Set("Authorization", "Bearer "+testCase.token)
+			}
+
+			rr := httptest.NewRecorder()
+			web.GetHandler().ServeHTTP(rr, req)
+
+			require.Equal(t, testCase.statusCode, rr.Code, rr.Body.String())
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/simulate", strings.NewReader(`{"eventType":"Reboot","notBefore":"5m"}`))
+	req.Header.Set("Authorization", "Bearer "+drainToken)
+
+	rr := httptest.NewRecorder()
+	web.GetHandler().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	response := struct {
+		Event types.ScheduledEventsEvent `json:"event"`
+	}{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+	select {
+	case event := <-receivedEvents:
+		require.Equal(t, response.Event.EventId, event.EventId)
+		require.True(t, event.IsSimulated())
+		require.Equal(t, types.EventTypeReboot, event.EventType)
+		require.Equal(t, []string{"test-resource"}, event.Resources)
+		require.InDelta(t, 5*time.Minute, event.TimeUntilStart(), float64(5*time.Second))
+	case <-time.After(5 * time.Second):
+		t.Fatal("simulated event was not received")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/status"
+)
+
+// output format for kubectl plugins and humans.
+const formatTable = "table"
+
+type readerStatus struct {
+	Config              json.RawMessage   `json:"config"`
+	LastPoll            events.PollResult `json:"lastPoll"`
+	LastSuccess         *time.Time        `json:"lastSuccess,omitempty"`
+	ConsecutiveFailures int               `json:"consecutiveFailures"`
+}
+
+type statusResponse struct {
+	Node          string                      `json:"node"`
+	Standalone    bool                        `json:"standalone"`
+	Version       string                      `json:"version"`
+	Reader        *readerStatus               `json:"reader,omitempty"`
+	Events        []status.EventRecord        `json:"events"`
+	PendingDrains []status.EventRecord        `json:"pendingDrains"`
+	Notifications []status.NotificationRecord `json:"notifications"`
+}
+
+func handlerStatus(w http.ResponseWriter, r *http.Request) {
+	response := statusResponse{
+		Node:          *config.Get().NodeName,
+		Standalone:    config.Get().IsStandalone(),
+		Version:       config.GetVersion(),
+		Events:        status.Events(),
+		PendingDrains: status.PendingEvents(),
+		Notifications: status.Notifications(),
+	}
+
+	if eventReader := getReader(); eventReader != nil {
+		response.Reader = &readerStatus{
+			Config:              json.RawMessage(eventReader.String()),
+			LastPoll:            eventReader.LastRead(),
+			ConsecutiveFailures: eventReader.ConsecutiveFailures(),
+		}
+
+		if lastSuccess := eventReader.LastSuccess(); !lastSuccess.IsZero() {
+			response.Reader.LastSuccess = &lastSuccess
+		}
+	}
+
+	if r.URL.Query().Get("format") == formatTable {
+		writeStatusTable(w, &response)
+
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+func handlerEvents(w http.ResponseWriter, r *http.Request) {
+	records := status.Events()
+
+	if r.URL.Query().Get("format") == formatTable {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		writeEventsTable(w, records)
+
+		return
+	}
+
+	writeJSON(w, records)
+}
+
+func writeStatusTable(w http.ResponseWriter, response *statusResponse) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0) //nolint:mnd
+
+	_, _ = fmt.Fprintf(tw, "NODE:\t%s\n", response.Node)
+	_, _ = fmt.Fprintf(tw, "STANDALONE:\t%t\n", response.Standalone)
+	_, _ = fmt.Fprintf(tw, "VERSION:\t%s\n", response.Version)
+
+	if response.Reader != nil {
+		lastPoll := response.Reader.LastPoll
+
+		result := "ok"
+		if len(lastPoll.Error) > 0 {
+			result = lastPoll.Error
+		}
+
+		_, _ = fmt.Fprintf(tw, "LAST POLL:\t%s\n", formatAge(lastPoll.Time))
+		_, _ = fmt.Fprintf(tw, "LAST POLL RESULT:\t%s\n", result)
+		_, _ = fmt.Fprintf(tw, "CONSECUTIVE FAILURES:\t%d\n", response.Reader.ConsecutiveFailures)
+
+		if lastPoll.Document != nil {
+			_, _ = fmt.Fprintf(tw, "DOCUMENT INCARNATION:\t%d\n", lastPoll.Document.DocumentIncarnation)
+			_, _ = fmt.Fprintf(tw, "DOCUMENT EVENTS:\t%d\n", len(lastPoll.Document.Events))
+		}
+	}
+
+	_, _ = fmt.Fprintf(tw, "PENDING DRAINS:\t%d\n", len(response.PendingDrains))
+
+	for _, notification := range response.Notifications {
+		result := "ok"
+		if len(notification.Error) > 0 {
+			result = notification.Error
+		}
+
+		_, _ = fmt.Fprintf(tw, "NOTIFICATION %s:\t%s %s (%s)\n", notification.Transport, notification.EventID, result, formatAge(notification.Time))
+	}
+
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintln(w)
+
+	writeEventsTable(w, response.Events)
+}
+
+func writeEventsTable(w io.Writer, records []status.EventRecord) {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0) //nolint:mnd
+
+	_, _ = fmt.Fprintln(tw, "EVENT ID\tTYPE\tSTATUS\tNOT BEFORE\tOUTCOME\tAGE\tERROR")
+
+	for _, record := range records {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Event.EventId,
+			record.Event.EventType,
+			valueOrNone(string(record.Event.EventStatus)),
+			valueOrNone(record.Event.NotBeforeString()),
+			record.Outcome,
+			formatAge(record.ReceivedAt),
+			valueOrNone(record.Error),
+		)
+	}
+
+	_ = tw.Flush()
+}
+
+func formatAge(value time.Time) string {
+	if value.IsZero() {
+		return "<never>"
+	}
+
+	return time.Since(value).Round(time.Second).String()
+}
+
+func valueOrNone(value string) string {
+	if len(value) == 0 {
+		return "<none>"
+	}
+
+	return value
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(value)
+}
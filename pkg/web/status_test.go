@@ -0,0 +1,84 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/events"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/status"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/types"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/web"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest
+func TestStatus(t *testing.T) {
+	nodeName := "test-node"
+	standalone := false
+
+	config.Set(config.Type{
+		NodeName:   &nodeName,
+		Standalone: &standalone,
+	})
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"DocumentIncarnation":3,"Events":[]}`))
+	}))
+	defer testServer.Close()
+
+	eventReader := events.NewReader()
+	eventReader.Endpoint = testServer.URL
+
+	_, err := eventReader.ReadEndpoint(context.TODO())
+	require.NoError(t, err)
+
+	web.SetReader(eventReader)
+	defer web.SetReader(nil)
+
+	status.RecordEvent(types.ScheduledEventsEvent{EventId: "status-event", EventType: types.EventTypeReboot})
+	status.SetEventOutcome("status-event", status.OutcomeWaitingLease, nil)
+
+	rr := httptest.NewRecorder()
+	web.GetHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	response := struct {
+		Node   string `json:"node"`
+		Reader struct {
+			LastPoll events.PollResult `json:"lastPoll"`
+		} `json:"reader"`
+		PendingDrains []status.EventRecord `json:"pendingDrains"`
+	}{}
+
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, nodeName, response.Node)
+	require.Equal(t, 3, response.Reader.LastPoll.Document.DocumentIncarnation)
+	require.Equal(t, "status-event", response.PendingDrains[len(response.PendingDrains)-1].Event.EventId)
+
+	rr = httptest.NewRecorder()
+	web.GetHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/events?format=table", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "EVENT ID")
+	require.Contains(t, rr.Body.String(), "status-event")
+	require.Contains(t, rr.Body.String(), string(status.OutcomeWaitingLease))
+
+	rr = httptest.NewRecorder()
+	web.GetHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/status?format=table", nil))
+	require.Contains(t, rr.Body.String(), "DOCUMENT INCARNATION:")
+}
@@ -0,0 +1,101 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// certificateReloader loads certificate again when files are changed,
+// for example when Kubernetes secret is updated.
+type certificateReloader struct {
+	certFile    string
+	keyFile     string
+	mutex       sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := reloader.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+func (c *certificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// files can be in the middle of update, previous certificate is used
+	if err := c.reloadIfChanged(); err != nil {
+		log.WithError(err).Error("error reloading TLS certificate")
+	}
+
+	return c.certificate, nil
+}
+
+func (c *certificateReloader) reloadIfChanged() error {
+	modTime, err := c.getModTime()
+	if err != nil {
+		return err
+	}
+
+	if !modTime.After(c.modTime) {
+		return nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "error in tls.LoadX509KeyPair")
+	}
+
+	if !c.modTime.IsZero() {
+		log.Infof("TLS certificate %s reloaded", c.certFile)
+	}
+
+	c.certificate = &certificate
+	c.modTime = modTime
+
+	return nil
+}
+
+// returns latest modification time of certificate and key.
+func (c *certificateReloader) getModTime() (time.Time, error) {
+	result := time.Time{}
+
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "error in os.Stat")
+		}
+
+		if info.ModTime().After(result) {
+			result = info.ModTime()
+		}
+	}
+
+	return result, nil
+}
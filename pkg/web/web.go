@@ -14,6 +14,7 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 	"net/http/pprof"
 	"time"
@@ -26,6 +27,30 @@ import (
 )
 
 func Start(ctx context.Context) {
+	var certificate *certificateReloader
+
+	if config.Get().IsWebTLS() {
+		reloader, err := newCertificateReloader(*config.Get().WebTLSCert, *config.Get().WebTLSKey)
+		if err != nil {
+			log.WithError(err).Fatal()
+		}
+
+		certificate = reloader
+	}
+
+	// all endpoints are served on one address by default
+	if len(*config.Get().WebAdminAddress) == 0 {
+		serve(ctx, *config.Get().WebHTTPAddress, GetHandler(), certificate)
+
+		return
+	}
+
+	go serve(ctx, *config.Get().WebAdminAddress, getAdminHandler(), certificate)
+
+	serve(ctx, *config.Get().WebHTTPAddress, getPublicHandler(), certificate)
+}
+
+func serve(ctx context.Context, address string, handler http.Handler, certificate *certificateReloader) {
 	const (
 		readTimeout    = 5 * time.Second
 		requestTimeout = 10 * time.Second
@@ -33,13 +58,20 @@ func Start(ctx context.Context) {
 	)
 
 	server := &http.Server{
-		Addr:         *config.Get().WebHTTPAddress,
-		Handler:      http.TimeoutHandler(GetHandler(), requestTimeout, "timeout"),
+		Addr:         address,
+		Handler:      http.TimeoutHandler(handler, requestTimeout, "timeout"),
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 	}
 
-	log.Info("web.address=", server.Addr)
+	if certificate != nil {
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certificate.GetCertificate,
+		}
+	}
+
+	log.Infof("web.address=%s, tls=%t", server.Addr, certificate != nil)
 
 	go func() {
 		<-ctx.Done()
@@ -50,26 +82,72 @@ func Start(ctx context.Context) {
 		_ = server.Shutdown(shutdownCtx) //nolint:contextcheck
 	}()
 
-	if err := server.ListenAndServe(); err != nil && ctx.Err() == nil {
+	var err error
+
+	if certificate != nil {
+		// certificate is from TLSConfig
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != nil && ctx.Err() == nil {
 		log.WithError(err).Fatal()
 	}
 }
 
+// GetHandler returns handler with all endpoints.
 func GetHandler() *http.ServeMux {
 	mux := http.NewServeMux()
 
+	addPublicHandlers(mux)
+	addAdminHandlers(mux)
+
+	return mux
+}
+
+func getPublicHandler() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	addPublicHandlers(mux)
+
+	return mux
+}
+
+func getAdminHandler() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	addAdminHandlers(mux)
+
+	return mux
+}
+
+// metrics and health checks.
+func addPublicHandlers(mux *http.ServeMux) {
 	mux.HandleFunc("/healthz", handlerHealthz)
-	mux.HandleFunc("/drainNode", handlerDrainNode)
+	mux.HandleFunc("/readyz", handlerReadyz)
+	mux.HandleFunc("/livez", handlerLivez)
 
 	mux.Handle("/metrics", metrics.GetHandler())
+}
 
-	mux.HandleFunc("/debug/pprof/", pprof.Index)
-	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+// endpoints that changes node or exposes internal state.
+func addAdminHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/status", handlerStatus)
+	mux.HandleFunc("/events", handlerEvents)
 
-	return mux
+	if config.Get().IsWebDrain() {
+		mux.HandleFunc("/drainNode", handlerDrainNode)
+		mux.HandleFunc("/simulate", handlerSimulate)
+	}
+
+	if config.Get().IsWebPprof() {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
 }
 
 func handlerHealthz(w http.ResponseWriter, r *http.Request) {
@@ -81,24 +159,15 @@ func handlerHealthz(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// check kubernetes API
-	if _, err := api.GetNode(r.Context(), *config.Get().NodeName); err != nil {
-		log.WithError(err).Error("kubernetes API is not available")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// check kubernetes API, there is no Kubernetes in standalone mode
+	if !config.Get().IsStandalone() {
+		if _, err := api.GetNode(r.Context(), *config.Get().NodeName); err != nil {
+			log.WithError(err).Error("kubernetes API is not available")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 
-		return
+			return
+		}
 	}
 
 	_, _ = w.Write([]byte("LIVE"))
 }
-
-func handlerDrainNode(w http.ResponseWriter, r *http.Request) {
-	err := api.DrainNode(r.Context(), *config.Get().NodeName, "Preempt", "manual")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-
-		return
-	}
-
-	_, _ = w.Write([]byte("done"))
-}
@@ -0,0 +1,160 @@
+/*
+Copyright paskal.maksim@gmail.com
+Licensed under the Apache License, Version 2.0 (the "License")
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package web_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/web"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest
+func TestDisabledEndpoints(t *testing.T) {
+	pprofEnabled := false
+	drainEnabled := false
+
+	config.Set(config.Type{
+		WebPprof: &pprofEnabled,
+		WebDrain: &drainEnabled,
+	})
+
+	for _, path := range []string{"/debug/pprof/", "/drainNode", "/simulate"} {
+		rr := httptest.NewRecorder()
+		web.GetHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, nil))
+
+		require.Equal(t, http.StatusNotFound, rr.Code, path)
+	}
+}
+
+//nolint:paralleltest,funlen
+func TestStartTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	writeCertificate(t, certFile, keyFile, "first")
+
+	address := getFreeAddress(t)
+	adminAddress := getFreeAddress(t)
+	gracePeriod := 1
+
+	config.Set(config.Type{
+		WebHTTPAddress:     &address,
+		WebAdminAddress:    &adminAddress,
+		WebTLSCert:         &certFile,
+		WebTLSKey:          &keyFile,
+		GracePeriodSeconds: &gracePeriod,
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	go web.Start(ctx)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+		},
+	}
+
+	getCommonName := func(address, path string) (string, int) {
+		var resp *http.Response
+
+		require.Eventually(t, func() bool {
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+address+path, nil)
+
+			var err error
+
+			resp, err = client.Do(req) //nolint:bodyclose
+
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		defer resp.Body.Close()
+
+		return resp.TLS.PeerCertificates[0].Subject.CommonName, resp.StatusCode
+	}
+
+	commonName, statusCode := getCommonName(address, "/livez")
+	require.Equal(t, "first", commonName)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	// admin endpoints are not served on public address
+	_, statusCode = getCommonName(address, "/status")
+	require.Equal(t, http.StatusNotFound, statusCode)
+
+	// health checks are not served on admin address
+	_, statusCode = getCommonName(adminAddress, "/livez")
+	require.Equal(t, http.StatusNotFound, statusCode)
+
+	// modification time must be changed
+	time.Sleep(10 * time.Millisecond)
+	writeCertificate(t, certFile, keyFile, "second")
+
+	client.CloseIdleConnections()
+
+	commonName, _ = getCommonName(address, "/livez")
+	require.Equal(t, "second", commonName)
+}
+
+func getFreeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	privateKey, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKey}), 0o600))
+}
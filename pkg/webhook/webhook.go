@@ -16,15 +16,20 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/config"
 	"github.com/maksim-paskal/aks-node-termination-handler/pkg/template"
+	"github.com/maksim-paskal/aks-node-termination-handler/pkg/utils"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
+// header is added to requests about simulated events.
+const simulatedHeader = "X-Simulated-Event"
+
 var client = &retryablehttp.Client{}
 
 var errHTTPNotOK = errors.New("http result not OK")
@@ -68,22 +73,27 @@ func SendWebHook(ctx context.Context, obj *template.MessageType) error {
 
 	requestBody := bytes.NewBufferString(webhookBody + "\n")
 
-	req, err := retryablehttp.NewRequest(*config.Get().WebHookMethod, *config.Get().WebHookURL, requestBody)
+	req, err := retryablehttp.NewRequestWithContext(ctx, *config.Get().WebHookMethod, *config.Get().WebHookURL, requestBody)
 	if err != nil {
-		return errors.Wrap(err, "error in retryablehttp.NewRequest")
+		return errors.Wrap(err, "error in retryablehttp.NewRequestWithContext")
 	}
 
 	req.Header.Set("Content-Type", *config.Get().WebHookContentType)
 
-	log.WithFields(log.Fields{
+	if message.Simulated {
+		req.Header.Set(simulatedHeader, "true")
+	}
+
+	log.WithContext(ctx).WithFields(log.Fields{
 		"method":  req.Method,
-		"url":     req.URL,
-		"headers": req.Header,
+		"url":     utils.RedactURL(req.URL.String()),
+		"headers": redactHeaders(req.Header),
 	}).Infof("Doing request with body: %s", requestBody.String())
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return errors.Wrap(err, "error in client.Do")
+		// error contains URL of request
+		return errors.Wrap(utils.RedactURLError(err, req.URL.String()), "error in client.Do")
 	}
 	defer resp.Body.Close()
 
@@ -95,3 +105,19 @@ func SendWebHook(ctx context.Context, obj *template.MessageType) error {
 
 	return nil
 }
+
+// headers that can contain credentials.
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// returns copy of headers that can be logged.
+func redactHeaders(headers http.Header) http.Header {
+	result := headers.Clone()
+
+	for _, header := range secretHeaders {
+		if len(result.Get(header)) > 0 {
+			result.Set(header, utils.Redacted)
+		}
+	}
+
+	return result
+}
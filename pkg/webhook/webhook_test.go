@@ -207,6 +207,14 @@ func TestWebHook(t *testing.T) { //nolint:funlen,tparallel
 			},
 			NodeName: "!!invalid!!GetNodeLabels",
 		},
+		{
+			Error:        true,
+			ErrorMessage: "http://127.0.0.1:1/<redacted>",
+			Name:         "RedactedURL",
+			Args: map[string]string{
+				"webhook.url": "http://127.0.0.1:1/secret-token",
+			},
+		},
 		{
 			Error:        true,
 			ErrorMessage: "error making roundtrip: proxyconnect tcp: dial tcp",
@@ -253,6 +261,7 @@ func TestWebHook(t *testing.T) { //nolint:funlen,tparallel
 			if tc.Error {
 				require.Error(t, err)
 				require.Contains(t, err.Error(), tc.ErrorMessage)
+				require.NotContains(t, err.Error(), "secret-token")
 			} else {
 				require.NoError(t, err)
 			}